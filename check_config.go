@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit codes of the check-config subcommand.
+const (
+	CheckConfigValid      = 0 // config file is valid
+	CheckConfigInvalid    = 1 // config file contains problems
+	CheckConfigUnreadable = 2 // config file could not be read
+)
+
+// ExitError carries the exit code the process should terminate with.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+var checkConfigCmd = cobra.Command{
+	Use:     "check-config",
+	Example: "./teltonika-exporter check-config --config path/to/config.yaml",
+	Short:   "Validate the config file",
+	Long: `Strictly decodes the config file and checks it for semantic problems like unknown sections,
+missing hosts or duplicate device names. Every problem is printed with its line number.
+
+Exit codes: 0 - config is valid, 1 - config contains problems, 2 - config file could not be read.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configFile == "" {
+			return &ExitError{Code: CheckConfigUnreadable, Err: fmt.Errorf("no config file specified")}
+		}
+
+		content, err := os.ReadFile(configFile)
+		if err != nil {
+			return &ExitError{Code: CheckConfigUnreadable, Err: fmt.Errorf("error reading config file: %w", err)}
+		}
+
+		problems := CheckConfig(content)
+		for _, problem := range problems {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s:%s\n", configFile, problem)
+		}
+
+		if len(problems) > 0 {
+			return &ExitError{Code: CheckConfigInvalid, Err: fmt.Errorf("config file contains %d problem(s)", len(problems))}
+		}
+
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: ok\n", configFile)
+		return nil
+	},
+}
+
+// ConfigProblem is a single finding of CheckConfig.
+type ConfigProblem struct {
+	Line    int
+	Message string
+}
+
+func (p ConfigProblem) String() string {
+	return fmt.Sprintf("%d: %s", p.Line, p.Message)
+}
+
+var yamlLineError = regexp.MustCompile(`line (\d+): (.*)`)
+
+// CheckConfig strictly decodes the config file content and validates it. Unlike ParseConfig
+// it rejects unknown fields and collects all problems instead of stopping at the first one.
+func CheckConfig(content []byte) []ConfigProblem {
+	var problems []ConfigProblem
+
+	config := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return []ConfigProblem{yamlProblem(err.Error())}
+		}
+
+		// type errors leave the rest of the config decoded, so semantic checks can still run
+		for _, message := range typeErr.Errors {
+			problems = append(problems, yamlProblem(message))
+		}
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return append(problems, yamlProblem(err.Error()))
+	}
+
+	root := &document
+	if len(document.Content) > 0 {
+		root = document.Content[0]
+	}
+
+	problems = append(problems, checkConfigSemantics(config, root)...)
+	slices.SortStableFunc(problems, func(a, b ConfigProblem) int {
+		return a.Line - b.Line
+	})
+
+	return problems
+}
+
+func checkConfigSemantics(config *Config, root *yaml.Node) []ConfigProblem {
+	var problems []ConfigProblem
+	report := func(node *yaml.Node, format string, args ...any) {
+		problems = append(problems, ConfigProblem{Line: node.Line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if err := validateCustomCollectors(config.CustomCollectors); err != nil {
+		report(cmp.Or(mappingKey(root, "custom_collectors"), root), "invalid custom collectors: %s", err)
+	}
+
+	if config.Influx != nil {
+		for _, err := range unjoin(config.Influx.Validate()) {
+			report(cmp.Or(mappingValue(root, "influx"), root), "influx: %s", err)
+		}
+	}
+
+	if config.Mqtt != nil {
+		for _, err := range unjoin(config.Mqtt.Validate()) {
+			report(cmp.Or(mappingValue(root, "mqtt"), root), "mqtt: %s", err)
+		}
+	}
+
+	if config.Otlp != nil {
+		for _, err := range unjoin(config.Otlp.Validate()) {
+			report(cmp.Or(mappingValue(root, "otlp"), root), "otlp: %s", err)
+		}
+	}
+
+	sections, err := config.Sections()
+	if err != nil {
+		sections = DefaultSections
+	}
+
+	devicesNode := mappingValue(root, "devices")
+	if len(config.Devices) == 0 || devicesNode == nil {
+		report(cmp.Or(mappingKey(root, "devices"), root), "no devices configured")
+		return problems
+	}
+
+	names := make(map[string]int)
+	for i, device := range config.Devices {
+		if i >= len(devicesNode.Content) {
+			break
+		}
+
+		node := devicesNode.Content[i]
+		name := cmp.Or(device.Name, device.Host, fmt.Sprintf("#%d", i+1))
+
+		if device.Host == "" {
+			report(node, "device %q: missing host", name)
+		}
+
+		if device.Username == "" {
+			report(node, "device %q: missing username", name)
+		}
+
+		if device.Password == "" {
+			report(node, "device %q: missing password", name)
+		}
+
+		if device.Schema != "" && device.Schema != "http" && device.Schema != "https" {
+			report(cmp.Or(mappingValue(node, "schema"), node), "device %q: unsupported schema %q, use \"http\" or \"https\"", name, device.Schema)
+		}
+
+		if device.Timeout < 0 {
+			report(cmp.Or(mappingValue(node, "timeout"), node), "device %q: timeout must not be negative", name)
+		}
+
+		if device.WirelessScanInterval < 0 {
+			report(cmp.Or(mappingValue(node, "wireless_scan_interval"), node), "device %q: wireless_scan_interval must not be negative", name)
+		}
+
+		if device.CardinalityLimit != nil && *device.CardinalityLimit < 0 {
+			report(cmp.Or(mappingValue(node, "cardinality_limit"), node), "device %q: cardinality_limit must not be negative", name)
+		}
+
+		if device.TopProcesses != nil && *device.TopProcesses < 0 {
+			report(cmp.Or(mappingValue(node, "top_processes"), node), "device %q: top_processes must not be negative", name)
+		}
+
+		nameNode := cmp.Or(mappingValue(node, "name"), mappingValue(node, "host"), node)
+		if line, ok := names[name]; ok {
+			report(nameNode, "device %q: duplicate device name, first defined on line %d", name, line)
+		} else {
+			names[name] = nameNode.Line
+		}
+
+		if len(device.Collect) == 0 {
+			report(node, "device %q: no sections to collect", name)
+		}
+
+		if device.Collect.Auto() {
+			continue
+		}
+
+		collectNode := mappingValue(node, "collect")
+		for j, section := range device.Collect {
+			if _, ok := sections.Get(section); ok {
+				continue
+			}
+
+			sectionNode := node
+			if collectNode != nil && j < len(collectNode.Content) {
+				sectionNode = collectNode.Content[j]
+			}
+			report(sectionNode, "device %q: unknown section %q, available sections: %s", name, section, strings.Join(sections.Names(), ", "))
+		}
+	}
+
+	return problems
+}
+
+// unjoin returns the errors joined by errors.Join, nil for no error.
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+
+	if err != nil {
+		return []error{err}
+	}
+
+	return nil
+}
+
+// yamlProblem converts a yaml error message containing a line number to a ConfigProblem.
+func yamlProblem(message string) ConfigProblem {
+	match := yamlLineError.FindStringSubmatch(message)
+	if match == nil {
+		return ConfigProblem{Message: message}
+	}
+
+	line, _ := strconv.Atoi(match[1])
+	return ConfigProblem{Line: line, Message: match[2]}
+}
+
+// mappingKey returns the key node of the given key in a yaml mapping node.
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+
+	return nil
+}
+
+// mappingValue returns the value node of the given key in a yaml mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		problems []ConfigProblem
+	}{
+		{
+			name: "valid",
+			config: `devices:
+  - host: "192.168.1.1"
+    username: "admin"
+    password: "admin"
+    collect: [ "system", "modem" ]
+`,
+		},
+		{
+			name: "auto collect",
+			config: `devices:
+  - host: "192.168.1.1"
+    username: "admin"
+    password: "admin"
+    collect: auto
+`,
+		},
+		{
+			name: "invalid collect scalar",
+			config: `devices:
+  - host: "192.168.1.1"
+    username: "admin"
+    password: "admin"
+    collect: everything
+`,
+			problems: []ConfigProblem{
+				{Line: 5, Message: `collect must be a list of sections or "auto"`},
+			},
+		},
+		{
+			name: "unknown field",
+			config: `devices:
+  - host: "192.168.1.1"
+    username: "admin"
+    password: "admin"
+    colect: [ "system" ]
+    collect: [ "system" ]
+`,
+			problems: []ConfigProblem{
+				{Line: 5, Message: "field colect not found in type main.DeviceConfig"},
+			},
+		},
+		{
+			name: "semantic problems",
+			config: `devices:
+  - name: "router"
+    host: "192.168.1.1"
+    schema: "htps"
+    username: "admin"
+    password: "admin"
+    collect: [ "system" ]
+  - name: "router"
+    username: "admin"
+    password: "admin"
+    collect: [ "system", "wireles" ]
+`,
+			problems: []ConfigProblem{
+				{Line: 4, Message: `device "router": unsupported schema "htps", use "http" or "https"`},
+				{Line: 8, Message: `device "router": missing host`},
+				{Line: 8, Message: `device "router": duplicate device name, first defined on line 2`},
+				{Line: 11, Message: `device "router": unknown section "wireles", available sections: system, modem, wireless, dhcp, wireless_scan, vpn, failover, ports, io, cpu, events, sms, hotspot, rms, services`},
+			},
+		},
+		{
+			name: "custom metric collides with builtin metric",
+			config: `custom_collectors:
+  - name: "uptime"
+    endpoint: "/system/device/usage/status"
+    metrics:
+      - name: "teltonika_device_uptime"
+        value: "data.uptime_seconds"
+devices:
+  - host: "192.168.1.1"
+    username: "admin"
+    password: "admin"
+    collect: [ "uptime" ]
+`,
+			problems: []ConfigProblem{
+				{Line: 1, Message: `invalid custom collectors: custom collector "uptime": metric "teltonika_device_uptime" is already exported by the exporter`},
+			},
+		},
+		{
+			name: "negative limits",
+			config: `devices:
+  - host: "192.168.1.1"
+    username: "admin"
+    password: "admin"
+    collect: [ "cpu", "wireless_scan" ]
+    wireless_scan_interval: "-30m"
+    cardinality_limit: -1
+    top_processes: -1
+`,
+			problems: []ConfigProblem{
+				{Line: 6, Message: `device "192.168.1.1": wireless_scan_interval must not be negative`},
+				{Line: 7, Message: `device "192.168.1.1": cardinality_limit must not be negative`},
+				{Line: 8, Message: `device "192.168.1.1": top_processes must not be negative`},
+			},
+		},
+		{
+			name: "invalid influx",
+			config: `influx:
+  url: "influxdb:8086/api/v2/write"
+  interval: "-1m"
+devices:
+  - host: "192.168.1.1"
+    username: "admin"
+    password: "admin"
+    collect: [ "system" ]
+`,
+			problems: []ConfigProblem{
+				{Line: 2, Message: "influx: url must be an absolute http or https URL"},
+				{Line: 2, Message: "influx: interval must not be negative"},
+			},
+		},
+		{
+			name: "invalid mqtt",
+			config: `mqtt:
+  broker: "tcp://localhost:1883"
+  qos: 3
+  home_assistant: true
+  interval: "-1m"
+devices:
+  - host: "192.168.1.1"
+    username: "admin"
+    password: "admin"
+    collect: [ "system" ]
+`,
+			problems: []ConfigProblem{
+				{Line: 2, Message: "mqtt: qos must be 0, 1 or 2"},
+				{Line: 2, Message: `mqtt: home_assistant discovery needs the "topics" format`},
+				{Line: 2, Message: "mqtt: interval must not be negative"},
+			},
+		},
+		{
+			name: "invalid otlp",
+			config: `otlp:
+  protocol: "udp"
+  interval: "-1m"
+devices:
+  - host: "192.168.1.1"
+    username: "admin"
+    password: "admin"
+    collect: [ "system" ]
+`,
+			problems: []ConfigProblem{
+				{Line: 2, Message: "otlp: missing endpoint"},
+				{Line: 2, Message: `otlp: unsupported protocol "udp", use "grpc" or "http"`},
+				{Line: 2, Message: "otlp: interval must not be negative"},
+			},
+		},
+		{
+			name:   "no devices",
+			config: "mac_translations: {}\n",
+			problems: []ConfigProblem{
+				{Line: 1, Message: "no devices configured"},
+			},
+		},
+		{
+			name:   "syntax error",
+			config: "devices:\n  - host: [\n",
+			problems: []ConfigProblem{
+				{Line: 2, Message: "did not find expected node content"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.problems, CheckConfig([]byte(tt.config)))
+		})
+	}
+}
+
+func TestCheckConfig_ExampleConfig(t *testing.T) {
+	content, err := os.ReadFile("deb/config.yaml")
+	assert.NoError(t, err)
+	assert.Empty(t, CheckConfig(content))
+}
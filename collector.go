@@ -3,33 +3,74 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 type Collector struct {
-	metrics Metrics
-	devices []*Device
+	metrics  Metrics
+	sections *SectionRegistry
+	devices  []*Device
 }
 
-func NewCollector(ctx context.Context, config *Config, metrics Metrics) *Collector {
+func NewCollector(ctx context.Context, config *Config, metrics Metrics) (*Collector, error) {
 	devices := make([]*Device, len(config.Devices))
 
+	registry, err := config.Sections()
+	if err != nil {
+		return nil, err
+	}
+
 	translator := &Translator{
 		mac:   config.MacTranslations,
 		radio: config.RadioTranslations,
+		vpn:   config.VpnTranslations,
+	}
+
+	var events *EventForwarder
+	if config.EventsOutput != "" {
+		events = NewEventForwarder(config.EventsOutput)
 	}
 
 	for i, device := range config.Devices {
+		topProcesses := 0
+		if device.TopProcesses != nil {
+			topProcesses = *device.TopProcesses
+		}
+
+		cardinalityLimit := 0
+		if device.CardinalityLimit != nil {
+			cardinalityLimit = *device.CardinalityLimit
+		}
+
+		var sections, candidates []Section
+		if device.Collect.Auto() {
+			candidates = registry.Sections()
+		} else {
+			sections, err = registry.Resolve(device.Collect)
+			if err != nil {
+				return nil, fmt.Errorf("device %q: %w", device.Name, err)
+			}
+		}
+
 		devices[i] = &Device{
 			name:     device.Name,
 			schema:   device.Schema,
 			host:     device.Host,
 			username: device.Username,
 			password: device.Password,
-			sections: device.Collect,
+			sections: sections,
+
+			candidates: candidates,
+
+			wirelessScanInterval: device.WirelessScanInterval,
+			cardinalityLimit:     cardinalityLimit,
+			topProcesses:         topProcesses,
 
 			client: &http.Client{
 				Timeout: device.Timeout,
@@ -43,6 +84,10 @@ func NewCollector(ctx context.Context, config *Config, metrics Metrics) *Collect
 			metrics:    metrics,
 			translator: translator,
 			token:      "",
+			now:        time.Now,
+
+			events:       events,
+			eventsCursor: -1,
 
 			ctx: ctx,
 			mtx: sync.Mutex{},
@@ -50,15 +95,14 @@ func NewCollector(ctx context.Context, config *Config, metrics Metrics) *Collect
 	}
 
 	return &Collector{
-		metrics: metrics,
-		devices: devices,
-	}
+		metrics:  metrics,
+		sections: registry,
+		devices:  devices,
+	}, nil
 }
 
 func (cc *Collector) Describe(ch chan<- *prometheus.Desc) {
-	for _, m := range cc.metrics {
-		ch <- m
-	}
+	cc.sections.Describe(ch)
 }
 
 func (cc *Collector) Collect(ch chan<- prometheus.Metric) {
@@ -74,3 +118,40 @@ func (cc *Collector) Collect(ch chan<- prometheus.Metric) {
 
 	wg.Wait()
 }
+
+// CachedGatherer shares a single collection between the push exporters (InfluxDB, MQTT, OTLP).
+// Gathering collects every device, so the exporters pushing on the same interval must not each
+// collect on their own. Results younger than maxAge are returned without a new collection.
+type CachedGatherer struct {
+	gatherer prometheus.Gatherer
+	maxAge   time.Duration
+	now      func() time.Time
+
+	mtx      sync.Mutex // held during the collection, concurrent callers wait for its result
+	families []*dto.MetricFamily
+	err      error
+	gathered time.Time
+}
+
+// NewCachedGatherer returns a gatherer reusing the results of the gatherer for maxAge.
+func NewCachedGatherer(gatherer prometheus.Gatherer, maxAge time.Duration) *CachedGatherer {
+	return &CachedGatherer{
+		gatherer: gatherer,
+		maxAge:   maxAge,
+		now:      time.Now,
+	}
+}
+
+// Gather returns the cached metric families, collecting them again when they are older than maxAge.
+// The returned families are shared by all callers and must not be modified.
+func (g *CachedGatherer) Gather() ([]*dto.MetricFamily, error) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.gathered.IsZero() || g.now().Sub(g.gathered) >= g.maxAge {
+		g.families, g.err = g.gatherer.Gather()
+		g.gathered = g.now()
+	}
+
+	return g.families, g.err
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedGatherer(t *testing.T) {
+	var gathers int
+	gatherer := NewCachedGatherer(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		gathers++
+		return []*dto.MetricFamily{{}}, nil
+	}), 30*time.Second)
+
+	now := time.Unix(1747248500, 0)
+	gatherer.now = func() time.Time { return now }
+
+	// the pushers of one interval share a single collection
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			families, err := gatherer.Gather()
+			assert.NoError(t, err)
+			assert.Len(t, families, 1)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, gathers)
+
+	now = now.Add(29 * time.Second)
+	_, err := gatherer.Gather()
+	require.NoError(t, err)
+	assert.Equal(t, 1, gathers)
+
+	// the next interval collects again
+	now = now.Add(time.Second)
+	_, err = gatherer.Gather()
+	require.NoError(t, err)
+	assert.Equal(t, 2, gathers)
+}
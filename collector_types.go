@@ -34,6 +34,46 @@ type ModemStatusResponse struct {
 	} `json:"data"`
 }
 
+type MessagesStatusResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		ID      string `json:"id"`
+		ModemID string `json:"modem_id"`
+		Sender  string `json:"sender"`
+		Date    string `json:"date"`
+		Status  string `json:"status"`
+	} `json:"data"`
+}
+
+type MessagesStorageStatusResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		ModemID string `json:"modem_id"`
+		Used    int    `json:"used"`
+		Total   int    `json:"total"`
+	} `json:"data"`
+}
+
+type SystemDeviceStatusResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Mnfinfo struct {
+			Mac    string `json:"mac"`
+			Serial string `json:"serial"`
+			Name   string `json:"name"` // product code
+			Batch  string `json:"batch"`
+			Hwver  string `json:"hwver"`
+		} `json:"mnfinfo"`
+		Static struct {
+			FwVersion  string `json:"fw_version"`
+			Model      string `json:"model"`
+			DeviceName string `json:"device_name"`
+			Hostname   string `json:"hostname"`
+			Kernel     string `json:"kernel"`
+		} `json:"static"`
+	} `json:"data"`
+}
+
 type SystemDeviceUsageStatusResponse struct {
 	Success bool `json:"success"`
 	Data    struct {
@@ -61,6 +101,53 @@ type SystemDeviceUsageStatusResponse struct {
 	} `json:"data"`
 }
 
+type CpuStatusResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Cores []struct {
+			Core   string  `json:"core"`
+			User   float64 `json:"user"`
+			System float64 `json:"system"`
+			Idle   float64 `json:"idle"`
+			Iowait float64 `json:"iowait"`
+		} `json:"cores"`
+	} `json:"data"`
+}
+
+type ProcessesStatusResponse struct {
+	Success bool            `json:"success"`
+	Data    []ProcessStatus `json:"data"`
+}
+
+type ProcessStatus struct {
+	Pid    int     `json:"pid"`
+	Name   string  `json:"name"`
+	Cpu    float64 `json:"cpu"`    // percent
+	Memory float64 `json:"memory"` // KiB
+}
+
+type NtpStatusResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Enabled      bool   `json:"enabled"`
+		Synchronized bool   `json:"synchronized"`
+		Server       string `json:"server"`
+		LastSync     int64  `json:"last_sync"`
+	} `json:"data"`
+}
+
+type IoStatusResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		ID    string  `json:"id"`
+		Name  string  `json:"name"`
+		Type  string  `json:"type"`
+		State string  `json:"state"`
+		Value float64 `json:"value"`
+		Unit  string  `json:"unit"`
+	} `json:"data"`
+}
+
 type DhcpLeasesStatusResponse struct {
 	Success bool          `json:"success"`
 	Data    []interface{} `json:"data"`
@@ -119,3 +206,136 @@ type WirelessInterfacesStatusResponse struct {
 		} `json:"clients"`
 	} `json:"data"`
 }
+
+type WirelessScanStatusResponse struct {
+	Success bool                 `json:"success"`
+	Data    []WirelessScanResult `json:"data"`
+}
+
+type WirelessScanResult struct {
+	Device     string `json:"device"`
+	Ssid       string `json:"ssid"`
+	Bssid      string `json:"bssid"`
+	Channel    int    `json:"channel"`
+	Signal     int    `json:"signal"`
+	Quality    int    `json:"quality"`
+	Encryption string `json:"encryption"`
+}
+
+type OpenVpnStatusResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		Name          string `json:"name"`
+		Type          string `json:"type"`
+		Status        string `json:"status"`
+		Remote        string `json:"remote"`
+		BytesReceived int64  `json:"bytes_received"`
+		BytesSent     int64  `json:"bytes_sent"`
+		Clients       []struct {
+			CommonName     string `json:"common_name"`
+			RealAddress    string `json:"real_address"`
+			VirtualAddress string `json:"virtual_address"`
+			BytesReceived  int64  `json:"bytes_received"`
+			BytesSent      int64  `json:"bytes_sent"`
+			ConnectedSince int64  `json:"connected_since"`
+		} `json:"clients"`
+	} `json:"data"`
+}
+
+type WireGuardStatusResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		Name  string `json:"name"`
+		Up    bool   `json:"up"`
+		Port  int    `json:"listen_port"`
+		Peers []struct {
+			Name            string `json:"name"`
+			PublicKey       string `json:"public_key"`
+			Endpoint        string `json:"endpoint"`
+			LatestHandshake int64  `json:"latest_handshake"`
+			RxBytes         int64  `json:"rx_bytes"`
+			TxBytes         int64  `json:"tx_bytes"`
+		} `json:"peers"`
+	} `json:"data"`
+}
+
+type IpsecStatusResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		Name     string `json:"name"`
+		State    string `json:"state"`
+		Remote   string `json:"remote"`
+		BytesIn  int64  `json:"bytes_in"`
+		BytesOut int64  `json:"bytes_out"`
+	} `json:"data"`
+}
+
+type FailoverStatusResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		Interface string `json:"interface"`
+		Enabled   bool   `json:"enabled"`
+		Active    bool   `json:"active"`
+		Status    string `json:"status"`
+		Track     struct {
+			State      string  `json:"state"`
+			HostsUp    int     `json:"hosts_up"`
+			HostsTotal int     `json:"hosts_total"`
+			Latency    float64 `json:"latency"`
+			Loss       float64 `json:"loss"`
+		} `json:"track"`
+	} `json:"data"`
+}
+
+type PortsStatusResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		Name   string `json:"name"`
+		Link   bool   `json:"link"`
+		Speed  int    `json:"speed"`
+		Duplex string `json:"duplex"`
+		Poe    *struct {
+			Enabled bool    `json:"enabled"`
+			Status  string  `json:"status"`
+			Power   float64 `json:"power"`
+		} `json:"poe"`
+	} `json:"data"`
+}
+
+type EventsLogStatusResponse struct {
+	Success bool `json:"success"`
+	Data    []struct {
+		ID       int64  `json:"id"`
+		Time     int64  `json:"time"`
+		Type     string `json:"type"`
+		Severity string `json:"severity"`
+		Message  string `json:"message"`
+	} `json:"data"`
+}
+
+type HotspotSessionsStatusResponse struct {
+	Success bool             `json:"success"`
+	Data    []HotspotSession `json:"data"`
+}
+
+type HotspotSession struct {
+	Instance      string `json:"instance"`
+	Mac           string `json:"mac"`
+	IP            string `json:"ip"`
+	Username      string `json:"username"`
+	Authenticated bool   `json:"authenticated"`
+	SessionTime   int64  `json:"session_time"`  // seconds
+	InputOctets   int64  `json:"input_octets"`  // received from the client
+	OutputOctets  int64  `json:"output_octets"` // sent to the client
+}
+
+type RmsStatusResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Enabled         bool   `json:"enabled"`
+		ConnectionState string `json:"connection_state"`
+		LastConnection  int64  `json:"last_connection"`
+		Hostname        string `json:"hostname"`
+		Port            int    `json:"port"`
+	} `json:"data"`
+}
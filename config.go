@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"time"
@@ -9,17 +10,64 @@ import (
 )
 
 type Config struct {
-	Devices []struct {
-		Name     string        `yaml:"name,omitempty"`
-		Schema   string        `yaml:"schema,omitempty"`
-		Host     string        `yaml:"host"`
-		Timeout  time.Duration `yaml:"timeout,omitempty"`
-		Username string        `yaml:"username"`
-		Password string        `yaml:"password"`
-		Collect  []string      `yaml:"collect"`
-	} `yaml:"devices"`
+	Devices           []DeviceConfig    `yaml:"devices"`
 	MacTranslations   map[string]string `yaml:"mac_translations,omitempty"`
 	RadioTranslations map[string]string `yaml:"radio_translations,omitempty"`
+	VpnTranslations   map[string]string `yaml:"vpn_translations,omitempty"`
+	EventsOutput      string            `yaml:"events_output,omitempty"`
+	Influx            *InfluxConfig     `yaml:"influx,omitempty"`
+	Mqtt              *MqttConfig       `yaml:"mqtt,omitempty"`
+	Otlp              *OtlpConfig       `yaml:"otlp,omitempty"`
+
+	CustomCollectors []CustomCollectorConfig `yaml:"custom_collectors,omitempty"`
+}
+
+// DeviceConfig describes a single router scraped by the exporter.
+type DeviceConfig struct {
+	Name     string        `yaml:"name,omitempty"`
+	Schema   string        `yaml:"schema,omitempty"`
+	Host     string        `yaml:"host"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+	Username string        `yaml:"username"`
+	Password string        `yaml:"password"`
+	Collect  CollectConfig `yaml:"collect"`
+
+	WirelessScanInterval time.Duration `yaml:"wireless_scan_interval,omitempty"`
+	CardinalityLimit     *int          `yaml:"cardinality_limit,omitempty"` // nil uses the default, 0 disables the limit
+	TopProcesses         *int          `yaml:"top_processes,omitempty"`     // nil uses the default, 0 disables per-process series
+
+	Attributes map[string]string `yaml:"attributes,omitempty"` // OTLP resource attributes, e.g. site
+}
+
+// CollectAuto in place of the section list makes the exporter probe the device for supported sections.
+const CollectAuto = "auto"
+
+// CollectConfig is the list of sections collected from a device. Besides a list it accepts
+// the scalar "auto", which enables section discovery.
+type CollectConfig []string
+
+func (c *CollectConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Value != CollectAuto {
+			return fmt.Errorf("line %d: collect must be a list of sections or %q", value.Line, CollectAuto)
+		}
+
+		*c = CollectConfig{CollectAuto}
+		return nil
+	}
+
+	var sections []string
+	if err := value.Decode(&sections); err != nil {
+		return err
+	}
+
+	*c = sections
+	return nil
+}
+
+// Auto reports whether the sections should be discovered.
+func (c CollectConfig) Auto() bool {
+	return len(c) == 1 && c[0] == CollectAuto
 }
 
 func ParseConfig(file string) (*Config, error) {
@@ -34,7 +82,73 @@ func ParseConfig(file string) (*Config, error) {
 		return nil, fmt.Errorf("error parsing config file: %w", err)
 	}
 
+	if err := validateCustomCollectors(config.CustomCollectors); err != nil {
+		return nil, fmt.Errorf("invalid custom collectors: %w", err)
+	}
+
+	sections, err := config.Sections()
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Influx != nil {
+		if err := config.Influx.Validate(); err != nil {
+			return nil, fmt.Errorf("influx: %w", err)
+		}
+	}
+
+	if config.Mqtt != nil {
+		if err := config.Mqtt.Validate(); err != nil {
+			return nil, fmt.Errorf("mqtt: %w", err)
+		}
+	}
+
+	if config.Otlp != nil {
+		if err := config.Otlp.Validate(); err != nil {
+			return nil, fmt.Errorf("otlp: %w", err)
+		}
+	}
+
+	for _, device := range config.Devices {
+		if device.WirelessScanInterval < 0 {
+			return nil, fmt.Errorf("device %q: wireless_scan_interval must not be negative", cmp.Or(device.Name, device.Host))
+		}
+
+		if device.CardinalityLimit != nil && *device.CardinalityLimit < 0 {
+			return nil, fmt.Errorf("device %q: cardinality_limit must not be negative", cmp.Or(device.Name, device.Host))
+		}
+
+		if device.TopProcesses != nil && *device.TopProcesses < 0 {
+			return nil, fmt.Errorf("device %q: top_processes must not be negative", cmp.Or(device.Name, device.Host))
+		}
+
+		if device.Collect.Auto() {
+			continue
+		}
+
+		if _, err := sections.Resolve(device.Collect); err != nil {
+			return nil, fmt.Errorf("device %q: %w", cmp.Or(device.Name, device.Host), err)
+		}
+	}
+
 	// reasonable defaults
+	if config.Influx != nil && config.Influx.Interval == 0 {
+		config.Influx.Interval = time.Minute
+	}
+
+	if config.Mqtt != nil {
+		config.Mqtt.ClientID = cmp.Or(config.Mqtt.ClientID, "teltonika-exporter")
+		config.Mqtt.Topic = cmp.Or(config.Mqtt.Topic, "teltonika")
+		config.Mqtt.Format = cmp.Or(config.Mqtt.Format, MqttFormatJson)
+		config.Mqtt.Interval = cmp.Or(config.Mqtt.Interval, time.Minute)
+		config.Mqtt.DiscoveryPrefix = cmp.Or(config.Mqtt.DiscoveryPrefix, "homeassistant")
+	}
+
+	if config.Otlp != nil {
+		config.Otlp.Protocol = cmp.Or(config.Otlp.Protocol, OtlpProtocolGrpc)
+		config.Otlp.Interval = cmp.Or(config.Otlp.Interval, time.Minute)
+	}
+
 	for key, device := range config.Devices {
 		if device.Name == "" {
 			config.Devices[key].Name = device.Host
@@ -47,7 +161,33 @@ func ParseConfig(file string) (*Config, error) {
 		if device.Timeout == 0 {
 			config.Devices[key].Timeout = 10 * time.Second
 		}
+
+		if device.WirelessScanInterval == 0 {
+			config.Devices[key].WirelessScanInterval = 30 * time.Minute
+		}
+
+		if device.CardinalityLimit == nil {
+			cardinalityLimit := 100
+			config.Devices[key].CardinalityLimit = &cardinalityLimit
+		}
+
+		if device.TopProcesses == nil {
+			topProcesses := 5
+			config.Devices[key].TopProcesses = &topProcesses
+		}
 	}
 
 	return config, nil
 }
+
+// Sections returns the builtin sections extended by custom collectors declared in the config.
+func (c *Config) Sections() (*SectionRegistry, error) {
+	sections := DefaultSections.Clone()
+	for _, collector := range NewCustomCollectors(c.CustomCollectors) {
+		if err := sections.Register(collector); err != nil {
+			return nil, fmt.Errorf("invalid custom collector: %w", err)
+		}
+	}
+
+	return sections, nil
+}
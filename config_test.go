@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig_CardinalityLimit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+devices:
+  - host: "192.168.1.1"
+    collect: [ "wireless_scan" ]
+  - host: "192.168.1.2"
+    collect: [ "wireless_scan" ]
+    cardinality_limit: 0
+`), 0o600))
+
+	config, err := ParseConfig(file)
+	require.NoError(t, err)
+	require.NotNil(t, config.Devices[0].CardinalityLimit)
+	assert.Equal(t, 100, *config.Devices[0].CardinalityLimit)
+	require.NotNil(t, config.Devices[1].CardinalityLimit)
+	assert.Equal(t, 0, *config.Devices[1].CardinalityLimit, "0 disables the limit")
+
+	require.NoError(t, os.WriteFile(file, []byte(`
+devices:
+  - host: "192.168.1.1"
+    collect: [ "wireless_scan" ]
+    cardinality_limit: -1
+`), 0o600))
+
+	_, err = ParseConfig(file)
+	require.ErrorContains(t, err, `device "192.168.1.1": cardinality_limit must not be negative`)
+}
+
+func TestParseConfig_InvalidPusher(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name: "influx",
+			config: `
+influx:
+  url: "http://influxdb:8086/api/v2/write"
+  interval: "-1m"
+`,
+			expected: "influx: interval must not be negative",
+		},
+		{
+			name: "mqtt",
+			config: `
+mqtt:
+  broker: "tcp://localhost:1883"
+  format: "topics"
+  interval: "-1m"
+`,
+			expected: "mqtt: interval must not be negative",
+		},
+		{
+			name: "otlp",
+			config: `
+otlp:
+  endpoint: "localhost:4317"
+  interval: "-1m"
+`,
+			expected: "otlp: interval must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(file, []byte(tt.config+`
+devices:
+  - host: "192.168.1.1"
+    collect: [ "system" ]
+`), 0o600))
+
+			_, err := ParseConfig(file)
+			require.EqualError(t, err, tt.expected)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var testCmd = cobra.Command{
+	Use:     "test",
+	Example: "./teltonika-exporter test --config path/to/config.yaml",
+	Short:   "Test connectivity to all configured devices",
+	Long: `Logs in to every configured device and collects each of its sections once, the same way a scrape does.
+Prints a table with HTTP status, latency, number of collected metrics and errors per section. Responses which
+are not JSON or report success false are errors as well.
+
+Exits with a non-zero code when a login or any section fails.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configFile == "" {
+			return fmt.Errorf("no config file specified")
+		}
+
+		config, err := ParseConfig(configFile)
+		if err != nil {
+			return fmt.Errorf("error parsing config file: %w", err)
+		}
+
+		collector, err := NewCollector(cmd.Context(), config, NewMetrics())
+		if err != nil {
+			return fmt.Errorf("error creating collector: %w", err)
+		}
+
+		var results []ProbeResult
+		for _, device := range collector.devices {
+			results = append(results, device.Probe()...)
+		}
+
+		if err := PrintProbeResults(cmd.OutOrStdout(), results); err != nil {
+			return err
+		}
+
+		if failed := slices.IndexFunc(results, func(r ProbeResult) bool { return r.Err != nil }); failed >= 0 {
+			return fmt.Errorf("connectivity test failed")
+		}
+
+		return nil
+	},
+}
+
+// ProbeResult is the outcome of a single step of the connectivity test - a login or a section collection.
+type ProbeResult struct {
+	Device   string
+	Section  string
+	Statuses []int
+	Latency  time.Duration
+	Metrics  int
+	Err      error
+}
+
+// probeLogin is the section name used for the login step in probe results.
+const probeLogin = "login"
+
+// Probe logs in to the device and collects every configured section once, recording
+// HTTP statuses of the requests made by each section. Devices with collect: auto discover
+// their sections first. A failed login skips the sections.
+func (d *Device) Probe() []ProbeResult {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	recorder, restore := d.recordRequests()
+	defer restore()
+
+	d.token = ""
+	start := time.Now()
+	err := d.authenticate()
+	results := []ProbeResult{{
+		Device:   d.name,
+		Section:  probeLogin,
+		Statuses: recorder.statuses(),
+		Latency:  time.Since(start),
+		Err:      cmp.Or(err, recorder.err()),
+	}}
+	if err != nil {
+		return results
+	}
+
+	if d.candidates != nil {
+		d.refreshSections()
+	}
+
+	for _, section := range d.sections {
+		recorder.reset()
+		start := time.Now()
+		metrics := len(d.collectSection(section, nil))
+
+		results = append(results, ProbeResult{
+			Device:   d.name,
+			Section:  section.Name(),
+			Statuses: recorder.statuses(),
+			Latency:  time.Since(start),
+			Metrics:  metrics,
+			Err:      recorder.err(),
+		})
+	}
+
+	return results
+}
+
+// PrintProbeResults writes the probe results as a table.
+func PrintProbeResults(w io.Writer, results []ProbeResult) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "DEVICE\tSECTION\tSTATUS\tLATENCY\tMETRICS\tERROR")
+	for _, result := range results {
+		statuses := make([]string, len(result.Statuses))
+		for i, status := range result.Statuses {
+			statuses[i] = strconv.Itoa(status)
+		}
+
+		message := "-"
+		if result.Err != nil {
+			message = result.Err.Error()
+		}
+
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			result.Device,
+			result.Section,
+			strings.Join(statuses, ","),
+			result.Latency.Round(time.Millisecond),
+			result.Metrics,
+			message,
+		)
+	}
+
+	return table.Flush()
+}
+
+type recordedRequest struct {
+	path   string
+	status int
+	err    error
+}
+
+// requestRecorder is a http.RoundTripper recording the outcome of every request passing through it.
+type requestRecorder struct {
+	next     http.RoundTripper
+	mtx      sync.Mutex
+	requests []recordedRequest
+}
+
+// recordRequests makes the device client record its requests until restore is called.
+func (d *Device) recordRequests() (recorder *requestRecorder, restore func()) {
+	recorder = &requestRecorder{next: d.client.Transport}
+	client := *d.client
+	client.Transport = recorder
+
+	original := d.client
+	d.client = &client
+
+	return recorder, func() { d.client = original }
+}
+
+func (r *requestRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := r.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	response, err := next.RoundTrip(req)
+
+	record := recordedRequest{path: req.URL.Path, err: err}
+	if response != nil {
+		record.status = response.StatusCode
+	}
+
+	// a successful status does not mean a usable response, the body is checked like Device.get does
+	if response != nil && response.StatusCode == http.StatusOK {
+		body, err := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		response.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err != nil {
+			record.err = err
+		} else {
+			record.err = responseError(body)
+		}
+	}
+
+	r.mtx.Lock()
+	r.requests = append(r.requests, record)
+	r.mtx.Unlock()
+
+	return response, err
+}
+
+func (r *requestRecorder) reset() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.requests = nil
+}
+
+// statuses returns the distinct HTTP statuses of the recorded requests.
+func (r *requestRecorder) statuses() []int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var statuses []int
+	for _, request := range r.requests {
+		if request.status != 0 && !slices.Contains(statuses, request.status) {
+			statuses = append(statuses, request.status)
+		}
+	}
+	slices.Sort(statuses)
+
+	return statuses
+}
+
+// err returns the first failure of the recorded requests. Missing endpoints are a failure
+// only when none of the requests succeeded, as many sections query optional endpoints.
+func (r *requestRecorder) err() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return requestsFailure(r.requests)
+}
+
+// errOf returns the first failure of the recorded requests to the endpoints.
+func (r *requestRecorder) errOf(endpoints []string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var requests []recordedRequest
+	for _, request := range r.requests {
+		if slices.ContainsFunc(endpoints, func(endpoint string) bool { return strings.HasSuffix(request.path, endpoint) }) {
+			requests = append(requests, request)
+		}
+	}
+
+	return requestsFailure(requests)
+}
+
+func requestsFailure(requests []recordedRequest) error {
+	notFound := 0
+	for _, request := range requests {
+		switch {
+		case request.err != nil:
+			return fmt.Errorf("%s: %w", request.path, request.err)
+		case request.status == http.StatusNotFound:
+			notFound++
+		case request.status != http.StatusOK:
+			return fmt.Errorf("%s: %d %s", request.path, request.status, http.StatusText(request.status))
+		}
+	}
+
+	if notFound > 0 && notFound == len(requests) {
+		return fmt.Errorf("not supported by the device: %w", ErrEndpointNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevice_Probe(t *testing.T) {
+	api := simulatorTransport(t, "tests", testsOptionalEndpoints...)
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/system/device/usage/status") {
+			return &http.Response{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error", Body: http.NoBody, Header: http.Header{}}, nil
+		}
+		return api.RoundTrip(req)
+	}), mustResolveSections(t, DefaultSections, SectionSystem, SectionServices)...)
+
+	results := d.Probe()
+	require.Len(t, results, 3)
+
+	assert.Equal(t, probeLogin, results[0].Section)
+	assert.Equal(t, []int{http.StatusOK}, results[0].Statuses)
+	assert.NoError(t, results[0].Err)
+
+	assert.Equal(t, SectionSystem, results[1].Section)
+	assert.Contains(t, results[1].Statuses, http.StatusInternalServerError)
+	assert.EqualError(t, results[1].Err, "/api/system/device/usage/status: 500 Internal Server Error")
+
+	// samba is not installed, the other services are
+	assert.Equal(t, SectionServices, results[2].Section)
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotFound}, results[2].Statuses)
+	assert.Positive(t, results[2].Metrics)
+	assert.NoError(t, results[2].Err)
+
+	var out bytes.Buffer
+	require.NoError(t, PrintProbeResults(&out, results))
+	assert.Contains(t, out.String(), "DEVICE  SECTION   STATUS")
+	assert.Contains(t, out.String(), "500 Internal Server Error")
+}
+
+func TestDevice_ProbeAuto(t *testing.T) {
+	probes := 0
+	d := testDevice(t, unsupportedRoundTripper(t, &probes))
+	d.candidates = mustResolveSections(t, DefaultSections, SectionSystem, SectionModem)
+
+	results := d.Probe()
+	require.Len(t, results, 2, "the discovered sections are probed")
+	assert.Equal(t, SectionSystem, results[1].Section)
+	assert.Positive(t, results[1].Metrics)
+}
+
+func TestDevice_ProbeInvalidResponse(t *testing.T) {
+	api := fixtureTransport(t)
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/io/status"):
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("<html>garbage")), Header: http.Header{}}, nil
+		case strings.HasSuffix(req.URL.Path, "/rms/status"):
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"success":false,"errors":[{"code":120,"error":"internal error"}]}`)), Header: http.Header{}}, nil
+		}
+		return api.RoundTrip(req)
+	}), mustResolveSections(t, DefaultSections, SectionIo, SectionRms)...)
+
+	results := d.Probe()
+	require.Len(t, results, 3)
+
+	assert.Equal(t, SectionIo, results[1].Section)
+	assert.Equal(t, []int{http.StatusOK}, results[1].Statuses)
+	assert.Zero(t, results[1].Metrics)
+	assert.EqualError(t, results[1].Err, "/api/io/status: response body is not valid JSON")
+
+	assert.Equal(t, SectionRms, results[2].Section)
+	assert.ErrorIs(t, results[2].Err, ErrUnsuccessful)
+	assert.Zero(t, results[2].Metrics, "failed responses do not export zeros")
+}
+
+func TestDevice_ProbeLoginFailure(t *testing.T) {
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized", Body: http.NoBody, Header: http.Header{}}, nil
+	}), mustResolveSections(t, DefaultSections, SectionSystem)...)
+
+	results := d.Probe()
+	require.Len(t, results, 1)
+	assert.Equal(t, []int{http.StatusUnauthorized}, results[0].Statuses)
+	assert.EqualError(t, results[0].Err, "authentication failed: 401 Unauthorized")
+}
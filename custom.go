@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	CustomMetricGauge   = "gauge"
+	CustomMetricCounter = "counter"
+)
+
+// CustomCollectorConfig declares a collector of an API endpoint which is not supported natively.
+// Values are selected from the response by gjson paths, see https://github.com/tidwall/gjson/blob/master/SYNTAX.md
+type CustomCollectorConfig struct {
+	Name     string               `yaml:"name"`            // section name used in collect
+	Endpoint string               `yaml:"endpoint"`        // API endpoint, e.g. /modems/status
+	Items    string               `yaml:"items,omitempty"` // path to an array, each element produces one sample (optional)
+	Metrics  []CustomMetricConfig `yaml:"metrics"`
+}
+
+type CustomMetricConfig struct {
+	Name   string            `yaml:"name"`
+	Type   string            `yaml:"type,omitempty"` // gauge (default) or counter
+	Help   string            `yaml:"help,omitempty"`
+	Value  string            `yaml:"value"`            // path to the value, relative to the item
+	Labels map[string]string `yaml:"labels,omitempty"` // label name -> path to the label value, relative to the item
+}
+
+// CustomCollector collects metrics from an endpoint declared in the config file.
+type CustomCollector struct {
+	name     string
+	endpoint string
+	items    string
+	metrics  []customMetric
+}
+
+type customMetric struct {
+	name       string
+	desc       *prometheus.Desc
+	valueType  prometheus.ValueType
+	value      string
+	labelPaths []string // in the same order as the desc labels, without the device label
+}
+
+// NewCustomCollectors creates collectors from the validated config.
+func NewCustomCollectors(configs []CustomCollectorConfig) []*CustomCollector {
+	collectors := make([]*CustomCollector, 0, len(configs))
+	for _, config := range configs {
+		collector := &CustomCollector{
+			name:     config.Name,
+			endpoint: config.Endpoint,
+			items:    config.Items,
+		}
+
+		for _, metric := range config.Metrics {
+			labelNames := make([]string, 0, len(metric.Labels))
+			for label := range metric.Labels {
+				labelNames = append(labelNames, label)
+			}
+			slices.Sort(labelNames)
+
+			labelPaths := make([]string, len(labelNames))
+			for i, label := range labelNames {
+				labelPaths[i] = metric.Labels[label]
+			}
+
+			valueType := prometheus.GaugeValue
+			if metric.Type == CustomMetricCounter {
+				valueType = prometheus.CounterValue
+			}
+
+			help := metric.Help
+			if help == "" {
+				help = fmt.Sprintf("Custom metric from %s", config.Endpoint)
+			}
+
+			collector.metrics = append(collector.metrics, customMetric{
+				name:       metric.Name,
+				desc:       prometheus.NewDesc(metric.Name, help, append([]string{"device"}, labelNames...), nil),
+				valueType:  valueType,
+				value:      metric.Value,
+				labelPaths: labelPaths,
+			})
+		}
+
+		collectors = append(collectors, collector)
+	}
+
+	return collectors
+}
+
+func (c *CustomCollector) Name() string {
+	return c.name
+}
+
+func (c *CustomCollector) Endpoints() []string {
+	return []string{c.endpoint}
+}
+
+func (c *CustomCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range c.metrics {
+		ch <- metric.desc
+	}
+}
+
+// validateCustomCollectors checks the custom collectors declared in the config file. Metric and
+// label names must follow the classic Prometheus naming rules and must not collide with builtin
+// metrics or each other, otherwise registering the collector would fail.
+func validateCustomCollectors(configs []CustomCollectorConfig) error {
+	names := make(map[string]bool)
+	metricNames := make(map[string]string) // metric name -> collector
+	for _, config := range configs {
+		if config.Name == "" {
+			return fmt.Errorf("custom collector without name")
+		}
+
+		if _, builtin := DefaultSections.Get(config.Name); builtin || names[config.Name] {
+			return fmt.Errorf("custom collector %q: name already used", config.Name)
+		}
+		names[config.Name] = true
+
+		if config.Endpoint == "" {
+			return fmt.Errorf("custom collector %q: missing endpoint", config.Name)
+		}
+
+		for _, metric := range config.Metrics {
+			if metric.Name == "" || metric.Value == "" {
+				return fmt.Errorf("custom collector %q: metric name and value are required", config.Name)
+			}
+
+			if metric.Type != "" && metric.Type != CustomMetricGauge && metric.Type != CustomMetricCounter {
+				return fmt.Errorf("custom collector %q: metric %q has unsupported type %q", config.Name, metric.Name, metric.Type)
+			}
+
+			if !model.IsValidLegacyMetricName(metric.Name) {
+				return fmt.Errorf("custom collector %q: invalid metric name %q", config.Name, metric.Name)
+			}
+
+			if _, builtin := builtinMetrics[metric.Name]; builtin {
+				return fmt.Errorf("custom collector %q: metric %q is already exported by the exporter", config.Name, metric.Name)
+			}
+
+			if collector, ok := metricNames[metric.Name]; ok {
+				return fmt.Errorf("custom collector %q: metric %q is already declared by custom collector %q", config.Name, metric.Name, collector)
+			}
+			metricNames[metric.Name] = config.Name
+
+			for label := range metric.Labels {
+				if !model.LabelName(label).IsValidLegacy() {
+					return fmt.Errorf("custom collector %q: metric %q has invalid label name %q", config.Name, metric.Name, label)
+				}
+
+				if label == "device" {
+					return fmt.Errorf("custom collector %q: metric %q uses the reserved label \"device\"", config.Name, metric.Name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *CustomCollector) Collect(d *Device, ch chan<- prometheus.Metric) {
+	var raw json.RawMessage
+	if err := d.get(c.endpoint, d.token, &raw); err != nil {
+		slog.Error("failed to get custom collector endpoint", "collector", c.name, "error", err)
+		return
+	}
+
+	items := []gjson.Result{gjson.ParseBytes(raw)}
+	if c.items != "" {
+		items = gjson.GetBytes(raw, c.items).Array()
+	}
+
+	for _, item := range items {
+		for _, metric := range c.metrics {
+			value := item.Get(metric.value)
+			if !value.Exists() {
+				continue
+			}
+
+			labels := make([]string, 0, len(metric.labelPaths)+1)
+			labels = append(labels, d.name)
+			for _, path := range metric.labelPaths {
+				labels = append(labels, item.Get(path).String())
+			}
+
+			m, err := prometheus.NewConstMetric(metric.desc, metric.valueType, customValue(value), labels...)
+			if err != nil {
+				slog.Error("failed to create custom metric", "collector", c.name, "error", err)
+				continue
+			}
+			ch <- m
+		}
+	}
+}
+
+// customValue converts the selected JSON value to a sample value, booleans are exported as 1/0.
+func customValue(value gjson.Result) float64 {
+	if value.Type == gjson.True || value.Type == gjson.False {
+		return boolToFloat(value.Bool())
+	}
+
+	return value.Float()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevice_CollectCustom(t *testing.T) {
+	registry := DefaultSections.Clone()
+	for _, collector := range NewCustomCollectors([]CustomCollectorConfig{
+		{
+			Name:     "modem_extra",
+			Endpoint: "/modems/status",
+			Items:    "data",
+			Metrics: []CustomMetricConfig{
+				{
+					Name:   "teltonika_custom_pin_left",
+					Help:   "Remaining PIN attempts",
+					Value:  "pinleft",
+					Labels: map[string]string{"sim": "id", "operator": "operator"},
+				},
+				{
+					Name:   "teltonika_custom_ipv6",
+					Value:  "ipv6",
+					Labels: map[string]string{"sim": "id"},
+				},
+				{
+					Name:  "teltonika_custom_missing",
+					Value: "does.not.exist",
+				},
+			},
+		},
+		{
+			Name:     "session",
+			Endpoint: "/session/status",
+			Metrics: []CustomMetricConfig{
+				{Name: "teltonika_custom_session_active", Type: CustomMetricCounter, Help: "Session", Value: "data.active"},
+			},
+		},
+	}) {
+		registry.MustRegister(collector)
+	}
+
+	d := testDevice(t, fixtureTransport(t), mustResolveSections(t, registry, "modem_extra", "session")...)
+	d.token = "secret_token"
+
+	expected := `
+# HELP teltonika_custom_ipv6 Custom metric from /modems/status
+# TYPE teltonika_custom_ipv6 gauge
+teltonika_custom_ipv6{device="RUT007",sim="2-1"} 1
+# HELP teltonika_custom_pin_left Remaining PIN attempts
+# TYPE teltonika_custom_pin_left gauge
+teltonika_custom_pin_left{device="RUT007",operator="O2.CZ",sim="2-1"} 3
+# HELP teltonika_custom_session_active Session
+# TYPE teltonika_custom_session_active counter
+teltonika_custom_session_active{device="RUT007"} 1
+`
+	err := testutil.CollectAndCompare(prometheus.CollectorFunc(d.Collect), strings.NewReader(expected),
+		"teltonika_custom_ipv6", "teltonika_custom_pin_left", "teltonika_custom_session_active", "teltonika_custom_missing")
+	require.NoError(t, err)
+
+	snapshot := d.Snapshot()
+	require.Len(t, snapshot.Sections, 2)
+	assert.Contains(t, snapshot.Sections[1].Metrics, MetricSnapshot{Name: "teltonika_custom_session_active", Value: 1})
+}
+
+func TestValidateCustomCollectors(t *testing.T) {
+	valid := CustomCollectorConfig{
+		Name:     "custom",
+		Endpoint: "/custom/status",
+		Metrics:  []CustomMetricConfig{{Name: "teltonika_custom", Value: "data.value"}},
+	}
+	require.NoError(t, validateCustomCollectors([]CustomCollectorConfig{valid}))
+
+	builtin := valid
+	builtin.Name = SectionModem
+	require.ErrorContains(t, validateCustomCollectors([]CustomCollectorConfig{builtin}), "already used")
+
+	require.ErrorContains(t, validateCustomCollectors([]CustomCollectorConfig{valid, valid}), "already used")
+
+	noEndpoint := valid
+	noEndpoint.Endpoint = ""
+	require.ErrorContains(t, validateCustomCollectors([]CustomCollectorConfig{noEndpoint}), "missing endpoint")
+
+	badType := valid
+	badType.Metrics = []CustomMetricConfig{{Name: "teltonika_custom", Value: "data.value", Type: "summary"}}
+	assert.ErrorContains(t, validateCustomCollectors([]CustomCollectorConfig{badType}), "unsupported type")
+
+	badName := valid
+	badName.Metrics = []CustomMetricConfig{{Name: "bad-name", Value: "data.value"}}
+	assert.ErrorContains(t, validateCustomCollectors([]CustomCollectorConfig{badName}), `invalid metric name "bad-name"`)
+
+	badLabel := valid
+	badLabel.Metrics = []CustomMetricConfig{{Name: "teltonika_custom", Value: "data.value", Labels: map[string]string{"sim-id": "id"}}}
+	assert.ErrorContains(t, validateCustomCollectors([]CustomCollectorConfig{badLabel}), `invalid label name "sim-id"`)
+
+	reservedLabel := valid
+	reservedLabel.Metrics = []CustomMetricConfig{{Name: "teltonika_custom", Value: "data.value", Labels: map[string]string{"device": "name"}}}
+	assert.ErrorContains(t, validateCustomCollectors([]CustomCollectorConfig{reservedLabel}), `reserved label "device"`)
+
+	builtinMetric := valid
+	builtinMetric.Metrics = []CustomMetricConfig{{Name: "teltonika_device_uptime", Value: "data.value"}}
+	assert.ErrorContains(t, validateCustomCollectors([]CustomCollectorConfig{builtinMetric}), "already exported by the exporter")
+
+	otherCollector := valid
+	otherCollector.Name = "other"
+	assert.ErrorContains(t, validateCustomCollectors([]CustomCollectorConfig{valid, otherCollector}), `already declared by custom collector "custom"`)
+}
@@ -10,4 +10,10 @@ chown teltonika-exporter:teltonika-exporter /var/lib/teltonika-exporter
 
 systemctl daemon-reload
 systemctl enable teltonika-exporter
-systemctl restart teltonika-exporter
\ No newline at end of file
+
+# do not restart a running exporter into a broken config
+if /usr/bin/teltonika-exporter check-config --config /etc/teltonika-exporter/config.yaml; then
+  systemctl restart teltonika-exporter
+else
+  echo "teltonika-exporter: invalid config /etc/teltonika-exporter/config.yaml, service was not restarted" >&2
+fi
\ No newline at end of file
@@ -2,24 +2,29 @@ package main
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-const (
-	SectionSystem   = "system"
-	SectionModem    = "modem"
-	SectionWireless = "wireless"
-	SectionDhcp     = "dhcp"
-)
+// ErrEndpointNotFound is returned when the device does not implement the requested API endpoint,
+// typically because the related package is not installed.
+var ErrEndpointNotFound = errors.New("endpoint not found")
+
+// ErrUnsuccessful is returned when the device answers a request with success false.
+var ErrUnsuccessful = errors.New("request was not successful")
 
 type Device struct {
 	name     string
@@ -27,12 +32,39 @@ type Device struct {
 	host     string
 	username string
 	password string
-	sections []string
+	sections []Section
+
+	candidates         []Section       // sections probed by collect: auto, nil when the sections are configured
+	discovered         map[string]bool // support of the probed candidates by name, failed probes are retried
+	discoveredFirmware string          // firmware version the sections were discovered on
+
+	wirelessScanInterval time.Duration // how often the neighbouring AP scan is refreshed
+	cardinalityLimit     int           // max number of series exported for unbounded label sets, 0 disables the limit
+	topProcesses         int           // number of the most demanding processes exported by the cpu section, 0 disables them
 
 	client     *http.Client
 	metrics    Metrics
 	translator *Translator
 	token      string
+	now        func() time.Time
+
+	info *SystemDeviceStatusResponse // cached per login, the static information does not change
+
+	wirelessScan        *WirelessScanStatusResponse // cached scan results
+	wirelessScanUpdated time.Time
+
+	failoverActive string         // WAN interface active during the previous scrape
+	failoverEvents map[string]int // count of switches to the WAN interface
+
+	portLinks map[string]bool // port link state seen during the previous scrape
+	portFlaps map[string]int  // count of port link state changes
+
+	events       *EventForwarder  // optional, forwards new events as JSON lines
+	eventsCursor int64            // ID of the last seen event, -1 before the first read
+	eventCounts  map[eventKey]int // count of new events by type and severity
+
+	snapshot    DeviceSnapshot // result of the last collection, served by the status API
+	snapshotMtx sync.RWMutex
 
 	ctx context.Context
 	mtx sync.Mutex
@@ -42,46 +74,51 @@ func (d *Device) Collect(ch chan<- prometheus.Metric) {
 	d.mtx.Lock()
 	defer d.mtx.Unlock()
 
+	collected := d.now()
+	recorder, restore := d.recordRequests()
+	defer restore()
+
 	if err := d.authenticate(); err != nil {
 		slog.Error("failed to authenticate", "error", err)
+		d.storeSnapshot(DeviceSnapshot{Info: d.deviceInfo()}, collected, err.Error())
 		return
 	}
 
+	if d.candidates != nil {
+		d.refreshSections()
+	}
+
+	sections := make([]SectionSnapshot, len(d.sections))
 	wg := sync.WaitGroup{}
-	for _, section := range d.sections {
-		switch section {
-		case SectionSystem:
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				d.collectSystemDeviceUsageStatus(ch)
-			}()
-		case SectionModem:
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				d.collectModemStatus(ch)
-			}()
-		case SectionWireless:
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				d.collectWirelessInterfacesStatus(ch)
-			}()
-		case SectionDhcp:
-			wg.Add(2)
-			go func() {
-				defer wg.Done()
-				d.collectDhcpLeasesIPv4Status(ch)
-			}()
-			go func() {
-				defer wg.Done()
-				d.collectDhcpLeasesIPv6Status(ch)
-			}()
-		}
+	wg.Add(len(d.sections))
+	for i, section := range d.sections {
+		go func() {
+			defer wg.Done()
+			sections[i] = SectionSnapshot{
+				Name:    section.Name(),
+				Metrics: d.collectSection(section, ch),
+			}
+		}()
 	}
 
 	wg.Wait()
+
+	// the sections log their own errors, failed requests are attributed to them by endpoint.
+	// Sections missing on the device are not an error, like optional endpoints.
+	var lastError string
+	for i, section := range d.sections {
+		err := recorder.errOf(section.Endpoints())
+		if errors.Is(err, ErrEndpointNotFound) {
+			slog.Debug("section is not available", "device", d.name, "section", section.Name())
+			continue
+		}
+		if err != nil {
+			sections[i].Error = err.Error()
+			lastError = cmp.Or(lastError, section.Name()+": "+err.Error())
+		}
+	}
+
+	d.storeSnapshot(DeviceSnapshot{LoggedIn: true, Info: d.deviceInfo(), Sections: sections}, collected, lastError)
 }
 
 func (d *Device) authenticate() error {
@@ -135,6 +172,7 @@ func (d *Device) authenticate() error {
 	}
 
 	d.token = output.Data.Token
+	d.info = nil // new login, firmware might have changed
 	return nil
 }
 
@@ -147,10 +185,6 @@ func (d *Device) checkCurrentToken() (bool, error) {
 		return false, fmt.Errorf("failed to get session status: %w", err)
 	}
 
-	if !status.Success {
-		return false, fmt.Errorf("token check failed")
-	}
-
 	if !status.Data.Active {
 		return false, nil // inactive token
 	}
@@ -158,6 +192,43 @@ func (d *Device) checkCurrentToken() (bool, error) {
 	return true, nil
 }
 
+// collectDeviceInfo exports static device information as part of the system section.
+// The information is requested only once per login.
+func (d *Device) collectDeviceInfo(ch chan<- prometheus.Metric) {
+	if err := d.loadInfo(); err != nil {
+		slog.Error("failed to get system device status", "error", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_device_info"],
+		prometheus.GaugeValue,
+		1,
+		d.name,
+		d.info.Data.Static.Model,
+		d.info.Data.Mnfinfo.Name,
+		d.info.Data.Mnfinfo.Serial,
+		d.info.Data.Static.FwVersion,
+		d.info.Data.Static.Hostname,
+		d.info.Data.Mnfinfo.Batch,
+	)
+}
+
+// loadInfo fetches the static device information unless it is cached for the current login.
+func (d *Device) loadInfo() error {
+	if d.info != nil {
+		return nil
+	}
+
+	var status SystemDeviceStatusResponse
+	if err := d.get("/system/device/status", d.token, &status); err != nil {
+		return err
+	}
+
+	d.info = &status
+	return nil
+}
+
 func (d *Device) collectModemStatus(ch chan<- prometheus.Metric) {
 	var status ModemStatusResponse
 	if err := d.get("/modems/status", d.token, &status); err != nil {
@@ -228,12 +299,88 @@ func (d *Device) collectModemStatus(ch chan<- prometheus.Metric) {
 	}
 }
 
+// collectMessagesStatus exports SMS counts per modem. The modem ID is used as the sim
+// label, so the metrics can be joined with the modem section.
+func (d *Device) collectMessagesStatus(ch chan<- prometheus.Metric) {
+	var status MessagesStatusResponse
+	if err := d.get("/messages/status", d.token, &status); err != nil {
+		slog.Error("failed to get messages status", "error", err)
+		return
+	}
+
+	counts := make(map[string]map[string]int) // modem -> status -> count
+	for _, message := range status.Data {
+		if counts[message.ModemID] == nil {
+			counts[message.ModemID] = map[string]int{"stored": 0, "unread": 0, "sent": 0, "failed": 0}
+		}
+
+		switch strings.ToLower(message.Status) {
+		case "unread":
+			counts[message.ModemID]["unread"]++
+			counts[message.ModemID]["stored"]++
+		case "read":
+			counts[message.ModemID]["stored"]++
+		case "sent":
+			counts[message.ModemID]["sent"]++
+			counts[message.ModemID]["stored"]++
+		case "failed":
+			counts[message.ModemID]["failed"]++
+		}
+	}
+
+	for modem, byStatus := range counts {
+		for messageStatus, count := range byStatus {
+			ch <- prometheus.MustNewConstMetric(
+				d.metrics["teltonika_sms_messages"],
+				prometheus.GaugeValue,
+				float64(count),
+				d.name, modem, messageStatus,
+			)
+		}
+	}
+}
+
+func (d *Device) collectMessagesStorageStatus(ch chan<- prometheus.Metric) {
+	var status MessagesStorageStatusResponse
+	if err := d.get("/messages/storage/status", d.token, &status); err != nil {
+		slog.Error("failed to get messages storage status", "error", err)
+		return
+	}
+
+	for _, storage := range status.Data {
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_sms_storage_used"],
+			prometheus.GaugeValue,
+			float64(storage.Used),
+			d.name, storage.ModemID,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_sms_storage_total"],
+			prometheus.GaugeValue,
+			float64(storage.Total),
+			d.name, storage.ModemID,
+		)
+	}
+}
+
 func (d *Device) collectSystemDeviceUsageStatus(ch chan<- prometheus.Metric) {
 	var status SystemDeviceUsageStatusResponse
+	start := d.now()
 	if err := d.get("/system/device/usage/status", d.token, &status); err != nil {
 		slog.Error("failed to get system device usage status", "error", err)
 		return
 	}
+	rtt := d.now().Sub(start)
+
+	// the device time was taken somewhere during the request, the middle of the round trip is the best guess
+	exporterTime := start.Add(rtt / 2)
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_device_clock_offset_seconds"],
+		prometheus.GaugeValue,
+		time.Unix(status.Data.Localtime, 0).Sub(exporterTime).Seconds(),
+		d.name,
+	)
 
 	ch <- prometheus.MustNewConstMetric(
 		d.metrics["teltonika_device_uptime"],
@@ -242,6 +389,13 @@ func (d *Device) collectSystemDeviceUsageStatus(ch chan<- prometheus.Metric) {
 		d.name,
 	)
 
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_device_boot_time_seconds"],
+		prometheus.GaugeValue,
+		float64(status.Data.Localtime-status.Data.UptimeSeconds),
+		d.name,
+	)
+
 	ch <- prometheus.MustNewConstMetric(
 		d.metrics["teltonika_cpu_usage"],
 		prometheus.GaugeValue,
@@ -273,51 +427,242 @@ func (d *Device) collectSystemDeviceUsageStatus(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(
 		d.metrics["teltonika_ram_total"],
 		prometheus.GaugeValue,
-		status.Data.Memory.RamTotal*1e6,
+		status.Data.Memory.RamTotal*systemMemoryUnit,
 		d.name,
 	)
 
 	ch <- prometheus.MustNewConstMetric(
 		d.metrics["teltonika_ram_used"],
 		prometheus.GaugeValue,
-		status.Data.Memory.RamUsed*1e6,
+		status.Data.Memory.RamUsed*systemMemoryUnit,
 		d.name,
 	)
 
 	ch <- prometheus.MustNewConstMetric(
 		d.metrics["teltonika_ram_free"],
 		prometheus.GaugeValue,
-		status.Data.Memory.RamFree*1e6,
+		status.Data.Memory.RamFree*systemMemoryUnit,
 		d.name,
 	)
 
 	ch <- prometheus.MustNewConstMetric(
 		d.metrics["teltonika_ram_buffered"],
 		prometheus.GaugeValue,
-		status.Data.Memory.RamBuffered*1e6,
+		status.Data.Memory.RamBuffered*systemMemoryUnit,
+		d.name,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_ram_shared"],
+		prometheus.GaugeValue,
+		status.Data.Memory.RamShared*systemMemoryUnit,
+		d.name,
+	)
+
+	// the API reports neither MemAvailable nor the page cache, so the figure of the router can not
+	// be exported. Free memory and buffers, like the -/+ buffers line of the old free(1), is the
+	// closest estimate and errs on the low side.
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_ram_available"],
+		prometheus.GaugeValue,
+		(status.Data.Memory.RamFree+status.Data.Memory.RamBuffered)*systemMemoryUnit,
+		d.name,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_ram_used_percent"],
+		prometheus.GaugeValue,
+		status.Data.Memory.RamPercentage,
 		d.name,
 	)
 
 	ch <- prometheus.MustNewConstMetric(
 		d.metrics["teltonika_flash_total"],
 		prometheus.GaugeValue,
-		status.Data.Memory.FlashTotal*1e6,
+		status.Data.Memory.FlashTotal*systemMemoryUnit,
 		d.name,
 	)
 
 	ch <- prometheus.MustNewConstMetric(
 		d.metrics["teltonika_flash_used"],
 		prometheus.GaugeValue,
-		status.Data.Memory.FlashUsed*1e6,
+		status.Data.Memory.FlashUsed*systemMemoryUnit,
 		d.name,
 	)
 
 	ch <- prometheus.MustNewConstMetric(
 		d.metrics["teltonika_flash_free"],
 		prometheus.GaugeValue,
-		status.Data.Memory.FlashFree*1e6,
+		status.Data.Memory.FlashFree*systemMemoryUnit,
 		d.name,
 	)
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_flash_used_percent"],
+		prometheus.GaugeValue,
+		status.Data.Memory.FlashPercentage,
+		d.name,
+	)
+}
+
+func (d *Device) collectCpuStatus(ch chan<- prometheus.Metric) {
+	var status CpuStatusResponse
+	if err := d.get("/system/cpu/status", d.token, &status); err != nil {
+		slog.Error("failed to get cpu status", "error", err)
+		return
+	}
+
+	for _, core := range status.Data.Cores {
+		modes := map[string]float64{
+			"user":   core.User,
+			"system": core.System,
+			"idle":   core.Idle,
+			"iowait": core.Iowait,
+		}
+
+		for mode, value := range modes {
+			ch <- prometheus.MustNewConstMetric(
+				d.metrics["teltonika_cpu_core_usage_percent"],
+				prometheus.GaugeValue,
+				value,
+				d.name, core.Core, mode,
+			)
+		}
+	}
+}
+
+// collectProcessesStatus exports the processes with the highest CPU and memory usage.
+// Processes are aggregated by name, so restarts do not create new series, and only
+// topProcesses names of each kind are exported to keep the cardinality bounded.
+func (d *Device) collectProcessesStatus(ch chan<- prometheus.Metric) {
+	var status ProcessesStatusResponse
+	if err := d.getOptional("/system/processes/status", &status); err != nil {
+		slog.Error("failed to get processes status", "error", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_processes"],
+		prometheus.GaugeValue,
+		float64(len(status.Data)),
+		d.name,
+	)
+
+	var byName []ProcessStatus
+	for _, process := range status.Data {
+		i := slices.IndexFunc(byName, func(p ProcessStatus) bool { return p.Name == process.Name })
+		if i < 0 {
+			byName = append(byName, ProcessStatus{Name: process.Name})
+			i = len(byName) - 1
+		}
+		byName[i].Cpu += process.Cpu
+		byName[i].Memory += process.Memory
+	}
+
+	byCpu := slices.Clone(byName)
+	slices.SortStableFunc(byCpu, func(a, b ProcessStatus) int {
+		return cmp.Compare(b.Cpu, a.Cpu)
+	})
+	byMemory := slices.Clone(byName)
+	slices.SortStableFunc(byMemory, func(a, b ProcessStatus) int {
+		return cmp.Compare(b.Memory, a.Memory)
+	})
+
+	top := max(d.topProcesses, 0)
+	var names []string
+	for _, process := range slices.Concat(byCpu[:min(top, len(byCpu))], byMemory[:min(top, len(byMemory))]) {
+		if slices.Contains(names, process.Name) {
+			continue
+		}
+		names = append(names, process.Name)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_process_cpu_percent"],
+			prometheus.GaugeValue,
+			process.Cpu,
+			d.name, process.Name,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_process_memory_bytes"],
+			prometheus.GaugeValue,
+			process.Memory*UnitKiB,
+			d.name, process.Name,
+		)
+	}
+}
+
+func (d *Device) collectIoStatus(ch chan<- prometheus.Metric) {
+	var status IoStatusResponse
+	if err := d.get("/io/status", d.token, &status); err != nil {
+		slog.Error("failed to get io status", "error", err)
+		return
+	}
+
+	for _, pin := range status.Data {
+		name := pin.Name
+		if name == "" {
+			name = pin.ID
+		}
+
+		if pin.Type == "analog_input" {
+			ch <- prometheus.MustNewConstMetric(
+				d.metrics["teltonika_io_analog_value"],
+				prometheus.GaugeValue,
+				pin.Value,
+				d.name, pin.ID, name, pin.Unit,
+			)
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_io_state"],
+			prometheus.GaugeValue,
+			boolToFloat(ioActive(pin.State)),
+			d.name, pin.ID, name, pin.Type,
+		)
+	}
+}
+
+// ioActive translates the textual pin state reported by the device to a boolean.
+func ioActive(state string) bool {
+	switch strings.ToLower(strings.TrimSpace(state)) {
+	case "1", "on", "high", "active", "closed":
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *Device) collectNtpStatus(ch chan<- prometheus.Metric) {
+	var status NtpStatusResponse
+	if err := d.getOptional("/date_time/ntp/client/status", &status); err != nil {
+		slog.Error("failed to get ntp client status", "error", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_ntp_enabled"],
+		prometheus.GaugeValue,
+		boolToFloat(status.Data.Enabled),
+		d.name,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_ntp_synchronized"],
+		prometheus.GaugeValue,
+		boolToFloat(status.Data.Synchronized),
+		d.name,
+	)
+
+	if status.Data.LastSync > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_ntp_last_sync_timestamp_seconds"],
+			prometheus.GaugeValue,
+			float64(status.Data.LastSync),
+			d.name,
+		)
+	}
 }
 
 func (d *Device) collectDhcpLeasesIPv4Status(ch chan<- prometheus.Metric) {
@@ -487,6 +832,708 @@ func (d *Device) collectWirelessInterfacesStatus(ch chan<- prometheus.Metric) {
 	}
 }
 
+// collectWirelessScanStatus exports neighbouring access points seen by the device radios.
+// The results of the last scan of the router are read, the exporter does not start scans
+// itself as a scan interrupts the clients of the radio. Reading is still expensive for the
+// router, so the results are cached and refreshed only once per wirelessScanInterval. When
+// a refresh fails, the cached results are exported.
+func (d *Device) collectWirelessScanStatus(ch chan<- prometheus.Metric) {
+	if d.wirelessScan == nil || d.now().Sub(d.wirelessScanUpdated) >= d.wirelessScanInterval {
+		var status WirelessScanStatusResponse
+		if err := d.get("/wireless/scan/status", d.token, &status); err != nil {
+			slog.Error("failed to get wireless scan status", "error", err)
+		} else {
+			// strongest networks first, so they survive the cardinality limit
+			slices.SortStableFunc(status.Data, func(a, b WirelessScanResult) int {
+				return b.Signal - a.Signal
+			})
+
+			d.wirelessScan = &status
+			d.wirelessScanUpdated = d.now()
+		}
+	}
+
+	if d.wirelessScan == nil {
+		return
+	}
+
+	networks := make(map[string]int)
+	for _, network := range d.wirelessScan.Data {
+		networks[network.Device]++
+	}
+
+	for radio, count := range networks {
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_wireless_scan_networks"],
+			prometheus.GaugeValue,
+			float64(count),
+			d.name, d.translator.TranslateRadio(radio),
+		)
+	}
+
+	results := d.wirelessScan.Data[:d.limit("wireless scan", len(d.wirelessScan.Data))]
+	for _, network := range results {
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_wireless_scan_signal"],
+			prometheus.GaugeValue,
+			float64(network.Signal),
+			d.name,
+			d.translator.TranslateRadio(network.Device),
+			strings.ToUpper(network.Bssid),
+			network.Ssid,
+			strconv.Itoa(network.Channel),
+			network.Encryption,
+		)
+	}
+}
+
+func (d *Device) collectOpenVpnStatus(ch chan<- prometheus.Metric) {
+	var status OpenVpnStatusResponse
+	if err := d.getOptional("/openvpn/status", &status); err != nil {
+		slog.Error("failed to get openvpn status", "error", err)
+		return
+	}
+
+	vpn := newVpnStatus("openvpn")
+	for _, instance := range status.Data {
+		tunnel := vpn.tunnel(d.translator.TranslateVpn(instance.Name))
+		up := strings.EqualFold(instance.Status, "connected") || strings.EqualFold(instance.Status, "running")
+		tunnel.up = tunnel.up || up
+
+		if instance.Type == "client" {
+			// client instance has exactly one peer - the server
+			if up {
+				tunnel.connected++
+			}
+			peer := vpn.peer(tunnel.name, d.translator.TranslateVpn(instance.Remote))
+			peer.rx += instance.BytesReceived
+			peer.tx += instance.BytesSent
+			continue
+		}
+
+		tunnel.connected += len(instance.Clients)
+		for _, client := range instance.Clients {
+			peer := vpn.peer(tunnel.name, d.translator.TranslateVpn(client.CommonName))
+			peer.rx += client.BytesReceived
+			peer.tx += client.BytesSent
+		}
+	}
+
+	d.collectVpn(ch, vpn)
+}
+
+// wireGuardHandshakeTimeout is the age of the latest handshake after which a WireGuard peer is
+// considered disconnected. Handshakes are renewed every two minutes while a tunnel is in use.
+const wireGuardHandshakeTimeout = 180 * time.Second
+
+func (d *Device) collectWireGuardStatus(ch chan<- prometheus.Metric) {
+	var status WireGuardStatusResponse
+	if err := d.getOptional("/wireguard/status", &status); err != nil {
+		slog.Error("failed to get wireguard status", "error", err)
+		return
+	}
+
+	vpn := newVpnStatus("wireguard")
+	for _, iface := range status.Data {
+		tunnel := vpn.tunnel(d.translator.TranslateVpn(iface.Name))
+		tunnel.up = tunnel.up || iface.Up
+
+		for _, p := range iface.Peers {
+			if p.LatestHandshake > 0 && d.now().Sub(time.Unix(p.LatestHandshake, 0)) <= wireGuardHandshakeTimeout {
+				tunnel.connected++
+			}
+
+			name := p.Name
+			if name == "" {
+				name = p.PublicKey
+			}
+
+			peer := vpn.peer(tunnel.name, d.translator.TranslateVpn(name))
+			peer.rx += p.RxBytes
+			peer.tx += p.TxBytes
+			peer.latestHandshake = max(peer.latestHandshake, p.LatestHandshake)
+		}
+	}
+
+	d.collectVpn(ch, vpn)
+}
+
+func (d *Device) collectIpsecStatus(ch chan<- prometheus.Metric) {
+	var status IpsecStatusResponse
+	if err := d.getOptional("/ipsec/status", &status); err != nil {
+		slog.Error("failed to get ipsec status", "error", err)
+		return
+	}
+
+	vpn := newVpnStatus("ipsec")
+	for _, conn := range status.Data {
+		tunnel := vpn.tunnel(d.translator.TranslateVpn(conn.Name))
+		up := strings.EqualFold(conn.State, "established") || strings.EqualFold(conn.State, "installed")
+		tunnel.up = tunnel.up || up
+		if up {
+			tunnel.connected++
+		}
+
+		peer := vpn.peer(tunnel.name, d.translator.TranslateVpn(conn.Remote))
+		peer.rx += conn.BytesIn
+		peer.tx += conn.BytesOut
+	}
+
+	d.collectVpn(ch, vpn)
+}
+
+// vpnStatus aggregates VPN tunnels and peers by their translated names. The names may collide,
+// e.g. two OpenVPN clients sharing a certificate, and every series must be exported once.
+type vpnStatus struct {
+	kind    string
+	tunnels []*vpnTunnel
+	peers   []*vpnPeer
+}
+
+type vpnTunnel struct {
+	name      string
+	up        bool // any of the instances is up
+	connected int
+}
+
+type vpnPeer struct {
+	tunnel, name    string
+	rx, tx          int64
+	latestHandshake int64 // WireGuard only, 0 without a handshake
+}
+
+func newVpnStatus(kind string) *vpnStatus {
+	return &vpnStatus{kind: kind}
+}
+
+// tunnel returns the tunnel with the name, adding it when it is new.
+func (s *vpnStatus) tunnel(name string) *vpnTunnel {
+	for _, tunnel := range s.tunnels {
+		if tunnel.name == name {
+			return tunnel
+		}
+	}
+
+	tunnel := &vpnTunnel{name: name}
+	s.tunnels = append(s.tunnels, tunnel)
+	return tunnel
+}
+
+// peer returns the peer of the tunnel with the name, adding it when it is new.
+func (s *vpnStatus) peer(tunnel, name string) *vpnPeer {
+	for _, peer := range s.peers {
+		if peer.tunnel == tunnel && peer.name == name {
+			return peer
+		}
+	}
+
+	peer := &vpnPeer{tunnel: tunnel, name: name}
+	s.peers = append(s.peers, peer)
+	return peer
+}
+
+func (d *Device) collectVpn(ch chan<- prometheus.Metric, vpn *vpnStatus) {
+	for _, tunnel := range vpn.tunnels {
+		d.collectVpnTunnel(ch, vpn.kind, tunnel.name, tunnel.up, tunnel.connected)
+	}
+
+	for _, peer := range vpn.peers {
+		d.collectVpnPeer(ch, vpn.kind, peer.tunnel, peer.name, peer.rx, peer.tx)
+
+		if peer.latestHandshake == 0 {
+			continue // no handshake yet
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_vpn_peer_last_handshake_age_seconds"],
+			prometheus.GaugeValue,
+			d.now().Sub(time.Unix(peer.latestHandshake, 0)).Seconds(),
+			d.name, vpn.kind, peer.tunnel, peer.name,
+		)
+	}
+}
+
+func (d *Device) collectVpnTunnel(ch chan<- prometheus.Metric, kind, tunnel string, up bool, peers int) {
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_vpn_up"],
+		prometheus.GaugeValue,
+		boolToFloat(up),
+		d.name, kind, tunnel,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_vpn_peers_connected"],
+		prometheus.GaugeValue,
+		float64(peers),
+		d.name, kind, tunnel,
+	)
+}
+
+func (d *Device) collectVpnPeer(ch chan<- prometheus.Metric, kind, tunnel, peer string, received, sent int64) {
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_vpn_peer_received_bytes_total"],
+		prometheus.CounterValue,
+		float64(received),
+		d.name, kind, tunnel, peer,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_vpn_peer_sent_bytes_total"],
+		prometheus.CounterValue,
+		float64(sent),
+		d.name, kind, tunnel, peer,
+	)
+}
+
+func (d *Device) collectFailoverStatus(ch chan<- prometheus.Metric) {
+	var status FailoverStatusResponse
+	if err := d.get("/failover/status", d.token, &status); err != nil {
+		slog.Error("failed to get failover status", "error", err)
+		return
+	}
+
+	active := ""
+	for _, iface := range status.Data {
+		if iface.Active {
+			active = iface.Interface
+		}
+	}
+	d.recordFailover(active)
+
+	for _, iface := range status.Data {
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_failover_interface_active"],
+			prometheus.GaugeValue,
+			boolToFloat(iface.Active),
+			d.name, iface.Interface,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_failover_interface_enabled"],
+			prometheus.GaugeValue,
+			boolToFloat(iface.Enabled),
+			d.name, iface.Interface,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_failover_interface_online"],
+			prometheus.GaugeValue,
+			boolToFloat(strings.EqualFold(iface.Status, "online")),
+			d.name, iface.Interface,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_failover_track_up"],
+			prometheus.GaugeValue,
+			boolToFloat(strings.EqualFold(iface.Track.State, "up")),
+			d.name, iface.Interface,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_failover_track_hosts_up"],
+			prometheus.GaugeValue,
+			float64(iface.Track.HostsUp),
+			d.name, iface.Interface,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_failover_ping_latency_seconds"],
+			prometheus.GaugeValue,
+			iface.Track.Latency/1000, // ms
+			d.name, iface.Interface,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_failover_ping_loss_percent"],
+			prometheus.GaugeValue,
+			iface.Track.Loss,
+			d.name, iface.Interface,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_failover_events_total"],
+			prometheus.CounterValue,
+			float64(d.failoverEvents[iface.Interface]),
+			d.name, iface.Interface,
+		)
+	}
+}
+
+// recordFailover counts a failover event every time the active WAN interface
+// differs from the one seen during the previous scrape.
+func (d *Device) recordFailover(active string) {
+	if d.failoverEvents == nil {
+		d.failoverEvents = make(map[string]int)
+	}
+
+	if d.failoverActive != "" && active != "" && active != d.failoverActive {
+		d.failoverEvents[active]++
+	}
+
+	if active != "" {
+		d.failoverActive = active
+	}
+}
+
+func (d *Device) collectPortsStatus(ch chan<- prometheus.Metric) {
+	var status PortsStatusResponse
+	if err := d.get("/ports/status", d.token, &status); err != nil {
+		slog.Error("failed to get ports status", "error", err)
+		return
+	}
+
+	for _, port := range status.Data {
+		d.recordPortLink(port.Name, port.Link)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_port_up"],
+			prometheus.GaugeValue,
+			boolToFloat(port.Link),
+			d.name, port.Name,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_port_link_flaps_total"],
+			prometheus.CounterValue,
+			float64(d.portFlaps[port.Name]),
+			d.name, port.Name,
+		)
+
+		if port.Poe != nil {
+			ch <- prometheus.MustNewConstMetric(
+				d.metrics["teltonika_port_poe_enabled"],
+				prometheus.GaugeValue,
+				boolToFloat(port.Poe.Enabled),
+				d.name, port.Name,
+			)
+
+			ch <- prometheus.MustNewConstMetric(
+				d.metrics["teltonika_port_poe_power_watts"],
+				prometheus.GaugeValue,
+				port.Poe.Power,
+				d.name, port.Name,
+			)
+		}
+
+		if !port.Link {
+			continue // speed and duplex are not negotiated
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_port_speed_bits_per_second"],
+			prometheus.GaugeValue,
+			float64(port.Speed)*1e6, // Mbps
+			d.name, port.Name,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_port_full_duplex"],
+			prometheus.GaugeValue,
+			boolToFloat(strings.EqualFold(port.Duplex, "full")),
+			d.name, port.Name,
+		)
+	}
+}
+
+// recordPortLink counts every change of the port link state between scrapes.
+func (d *Device) recordPortLink(port string, up bool) {
+	if d.portLinks == nil {
+		d.portLinks = make(map[string]bool)
+		d.portFlaps = make(map[string]int)
+	}
+
+	if previous, ok := d.portLinks[port]; ok && previous != up {
+		d.portFlaps[port]++
+	}
+
+	d.portLinks[port] = up
+}
+
+type eventKey struct {
+	Type     string
+	Severity string
+}
+
+// collectEventsStatus counts events logged by the device since the last seen event.
+// Events present during the first read are considered history and are not counted.
+func (d *Device) collectEventsStatus(ch chan<- prometheus.Metric) {
+	var status EventsLogStatusResponse
+	if err := d.get("/events_log/status", d.token, &status); err != nil {
+		slog.Error("failed to get events log status", "error", err)
+		return
+	}
+
+	if d.eventCounts == nil {
+		d.eventCounts = make(map[eventKey]int)
+	}
+
+	latest := int64(0)
+	for _, event := range status.Data {
+		latest = max(latest, event.ID)
+	}
+
+	if latest < d.eventsCursor {
+		d.eventsCursor = 0 // log was cleared, e.g. after factory reset
+	}
+
+	var forward []ForwardedEvent
+	if d.eventsCursor >= 0 {
+		for _, event := range status.Data {
+			if event.ID <= d.eventsCursor {
+				continue // already seen
+			}
+
+			d.eventCounts[eventKey{Type: event.Type, Severity: event.Severity}]++
+			forward = append(forward, ForwardedEvent{
+				Device:   d.name,
+				ID:       event.ID,
+				Time:     event.Time,
+				Type:     event.Type,
+				Severity: event.Severity,
+				Message:  event.Message,
+			})
+		}
+	}
+	d.eventsCursor = latest
+
+	if d.events != nil {
+		slices.SortFunc(forward, func(a, b ForwardedEvent) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+		if err := d.events.Forward(forward); err != nil {
+			slog.Error("failed to forward events", "device", d.name, "error", err)
+		}
+	}
+
+	for key, count := range d.eventCounts {
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_events_total"],
+			prometheus.CounterValue,
+			float64(count),
+			d.name, key.Type, key.Severity,
+		)
+	}
+}
+
+func (d *Device) collectRmsStatus(ch chan<- prometheus.Metric) {
+	var status RmsStatusResponse
+	if err := d.get("/rms/status", d.token, &status); err != nil {
+		slog.Error("failed to get rms status", "error", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_rms_info"],
+		prometheus.GaugeValue,
+		1,
+		d.name, status.Data.Hostname, strconv.Itoa(status.Data.Port),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_rms_enabled"],
+		prometheus.GaugeValue,
+		boolToFloat(status.Data.Enabled),
+		d.name,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		d.metrics["teltonika_rms_connected"],
+		prometheus.GaugeValue,
+		boolToFloat(strings.EqualFold(status.Data.ConnectionState, "connected")),
+		d.name,
+	)
+
+	if status.Data.LastConnection > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_rms_last_connection_timestamp_seconds"],
+			prometheus.GaugeValue,
+			float64(status.Data.LastConnection),
+			d.name,
+		)
+	}
+}
+
+func (d *Device) collectServiceStatus(ch chan<- prometheus.Metric, service Service) {
+	var status ServiceStatusResponse
+	if err := d.getOptional(service.Endpoint, &status); err != nil {
+		slog.Error("failed to get service status", "service", service.Name, "error", err)
+		return
+	}
+
+	for _, instance := range status.Instances() {
+		name := service.Name
+		if service.Instance != "" {
+			name, _ = instance[service.Instance].(string)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_service_enabled"],
+			prometheus.GaugeValue,
+			boolToFloat(serviceStateTrue(instance[service.Enabled])),
+			d.name, service.Name, name,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_service_running"],
+			prometheus.GaugeValue,
+			boolToFloat(serviceStateTrue(instance[service.Running])),
+			d.name, service.Name, name,
+		)
+
+		if service.LastUpdate == "" {
+			continue
+		}
+
+		if lastUpdate, ok := instance[service.LastUpdate].(float64); ok && lastUpdate > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				d.metrics["teltonika_service_last_update_timestamp_seconds"],
+				prometheus.GaugeValue,
+				lastUpdate,
+				d.name, service.Name, name,
+			)
+		}
+	}
+}
+
+func (d *Device) collectHotspotSessionsStatus(ch chan<- prometheus.Metric) {
+	var status HotspotSessionsStatusResponse
+	if err := d.get("/hotspot/sessions/status", d.token, &status); err != nil {
+		slog.Error("failed to get hotspot sessions status", "error", err)
+		return
+	}
+
+	// the durations describe the sessions active now, they are gauges and not a histogram,
+	// which would drop whenever a session ends
+	type instanceStats struct {
+		sessions      int
+		authenticated int
+		durationSum   int64
+		durationMax   int64
+	}
+
+	instances := make(map[string]*instanceStats)
+	for _, session := range status.Data {
+		stats, ok := instances[session.Instance]
+		if !ok {
+			stats = &instanceStats{}
+			instances[session.Instance] = stats
+		}
+
+		stats.sessions++
+		if session.Authenticated {
+			stats.authenticated++
+		}
+
+		stats.durationSum += session.SessionTime
+		stats.durationMax = max(stats.durationMax, session.SessionTime)
+	}
+
+	for instance, stats := range instances {
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_hotspot_sessions"],
+			prometheus.GaugeValue,
+			float64(stats.sessions),
+			d.name, instance,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_hotspot_authenticated_users"],
+			prometheus.GaugeValue,
+			float64(stats.authenticated),
+			d.name, instance,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_hotspot_session_max_duration_seconds"],
+			prometheus.GaugeValue,
+			float64(stats.durationMax),
+			d.name, instance,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_hotspot_session_average_duration_seconds"],
+			prometheus.GaugeValue,
+			float64(stats.durationSum)/float64(stats.sessions),
+			d.name, instance,
+		)
+	}
+
+	// sessions are aggregated per client, a client may have several sessions and
+	// translated MAC addresses may collide
+	type clientTraffic struct {
+		instance, client string
+		input, output    int64
+	}
+
+	var clients []*clientTraffic
+	byClient := make(map[[2]string]*clientTraffic)
+	for _, session := range status.Data {
+		key := [2]string{session.Instance, d.translator.TranslateMac(session.Mac)}
+		traffic, ok := byClient[key]
+		if !ok {
+			traffic = &clientTraffic{instance: key[0], client: key[1]}
+			byClient[key] = traffic
+			clients = append(clients, traffic)
+		}
+
+		traffic.input += session.InputOctets
+		traffic.output += session.OutputOctets
+	}
+
+	// the most active clients first, so they survive the cardinality limit
+	slices.SortStableFunc(clients, func(a, b *clientTraffic) int {
+		return cmp.Compare(b.input+b.output, a.input+a.output)
+	})
+
+	for _, traffic := range clients[:d.limit("hotspot clients", len(clients))] {
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_hotspot_client_received_bytes_total"],
+			prometheus.CounterValue,
+			float64(traffic.input),
+			d.name, traffic.instance, traffic.client,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			d.metrics["teltonika_hotspot_client_sent_bytes_total"],
+			prometheus.CounterValue,
+			float64(traffic.output),
+			d.name, traffic.instance, traffic.client,
+		)
+	}
+}
+
+// getOptional calls the endpoint of a feature which does not have to be installed on the device.
+// Missing endpoints are not considered an error and leave the response empty.
+func (d *Device) getOptional(endpoint string, response interface{}) error {
+	err := d.get(endpoint, d.token, response)
+	if errors.Is(err, ErrEndpointNotFound) {
+		slog.Debug("endpoint is not available", "device", d.name, "endpoint", endpoint)
+		return nil
+	}
+
+	return err
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// limit returns how many of the count items can be exported without exceeding
+// the cardinality limit of the device, 0 exports all of them. Dropped items are logged.
+func (d *Device) limit(what string, count int) int {
+	if d.cardinalityLimit <= 0 || count <= d.cardinalityLimit {
+		return count
+	}
+
+	slog.Warn("cardinality limit reached, dropping series",
+		"device", d.name, "what", what, "count", count, "limit", d.cardinalityLimit)
+	return d.cardinalityLimit
+}
+
 func (d *Device) get(endpoint, token string, response interface{}) error {
 	slog.Debug("Calling API", "url", d.buildUrl(endpoint))
 
@@ -508,6 +1555,10 @@ func (d *Device) get(endpoint, token string, response interface{}) error {
 		}
 	}()
 
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("failed to get %s: %w", endpoint, ErrEndpointNotFound)
+	}
+
 	if httpResponse.StatusCode != http.StatusOK {
 		return fmt.Errorf("failed to get %s: %s", endpoint, httpResponse.Status)
 	}
@@ -517,14 +1568,39 @@ func (d *Device) get(endpoint, token string, response interface{}) error {
 		return fmt.Errorf("failed to read httpResponse body: %w", err)
 	}
 
+	if err := responseError(responseBody); err != nil {
+		return fmt.Errorf("failed to get %s: %w", endpoint, err)
+	}
+
 	if err := json.Unmarshal(responseBody, response); err != nil {
-		fmt.Println(string(responseBody))
 		return fmt.Errorf("failed to unmarshal response body: %w", err)
 	}
 
 	return nil
 }
 
+// responseError returns the failure reported by the body of a successful HTTP response. Broken
+// firmware answers with 200 and a body which is not JSON, or with success false and the errors.
+func responseError(body []byte) error {
+	if !json.Valid(body) {
+		return errors.New("response body is not valid JSON")
+	}
+
+	var response struct {
+		Success *bool           `json:"success"`
+		Errors  json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Success == nil || *response.Success {
+		return nil // not an API response envelope, e.g. of a custom endpoint
+	}
+
+	if len(response.Errors) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnsuccessful, response.Errors)
+	}
+
+	return ErrUnsuccessful
+}
+
 func (d *Device) buildUrl(endpoint string) string {
 	return fmt.Sprintf("%s://%s/api%s", d.schema, d.host, endpoint)
 }
@@ -2,13 +2,12 @@ package main
 
 import (
 	"bytes"
-	"fmt"
-	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
-	"sync"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
@@ -17,27 +16,27 @@ import (
 )
 
 func TestDevice_Collect(t *testing.T) {
-	d := Device{
-		name:     "RUT007",
-		schema:   "https",
-		host:     "localhost",
-		username: "root",
-		password: "pw",
-		sections: []string{SectionModem, SectionDhcp, SectionSystem, SectionWireless},
-		client:   mockHttpClient(t),
-		metrics:  NewMetrics(),
-		translator: &Translator{
-			mac: map[string]string{
-				"14:25:36:AB:AA:44": "iphone",
-			},
-			radio: map[string]string{
-				"radio0": "wifi_2.4",
-			},
+	d := testDevice(t, fixtureTransport(t), mustResolveSections(t, DefaultSections,
+		SectionModem, SectionDhcp, SectionSystem, SectionWireless,
+		SectionWirelessScan, SectionVpn, SectionFailover, SectionPorts, SectionIo,
+		SectionCpu, SectionEvents, SectionSms, SectionHotspot, SectionRms,
+		SectionServices,
+	)...)
+	d.username = "root"
+	d.password = "pw"
+	d.wirelessScanInterval = 30 * time.Minute
+	d.cardinalityLimit = 2
+	d.topProcesses = 1
+	d.translator = &Translator{
+		mac: map[string]string{
+			"14:25:36:AB:AA:44": "iphone",
+		},
+		radio: map[string]string{
+			"radio0": "wifi_2.4",
+		},
+		vpn: map[string]string{
+			"wg0": "hq",
 		},
-		token: "",
-
-		ctx: t.Context(),
-		mtx: sync.Mutex{},
 	}
 
 	expected, err := os.ReadFile("tests/metrics.txt")
@@ -47,88 +46,291 @@ func TestDevice_Collect(t *testing.T) {
 	require.NoError(t, err)
 }
 
-type RoundTripperMock struct {
-	T *testing.T
+func TestDevice_Limit(t *testing.T) {
+	d := testDevice(t, fixtureTransport(t))
+
+	d.cardinalityLimit = 2
+	assert.Equal(t, 1, d.limit("clients", 1))
+	assert.Equal(t, 2, d.limit("clients", 3))
+
+	d.cardinalityLimit = 0
+	assert.Equal(t, 1000, d.limit("clients", 1000), "0 disables the limit")
 }
 
-func (m *RoundTripperMock) RoundTrip(req *http.Request) (*http.Response, error) {
-	if strings.HasSuffix(req.URL.String(), "/login") {
-		content, err := os.ReadFile("tests/login.json")
-		assert.NoError(m.T, err)
+func TestDevice_CollectWirelessScanCache(t *testing.T) {
+	api := simulatorTransport(t, "tests", testsOptionalEndpoints...)
+	scans := 0
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.String(), "/wireless/scan/status") {
+			scans++
+		}
+		return api.RoundTrip(req)
+	}), mustResolveSections(t, DefaultSections, SectionWirelessScan)...)
+	d.wirelessScanInterval = time.Hour
 
-		return &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(bytes.NewReader(content)),
-		}, nil
+	for range 3 {
+		assert.Equal(t, 5, testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect)))
 	}
+	assert.Equal(t, 1, scans, "scan results should be served from cache")
 
-	if strings.HasSuffix(req.URL.String(), "/session/status") {
-		content, err := os.ReadFile("tests/session_status.json")
-		assert.NoError(m.T, err)
+	d.wirelessScanUpdated = mockNow().Add(-2 * time.Hour)
+	assert.Equal(t, 5, testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect)))
+	assert.Equal(t, 2, scans, "expired scan results should be refreshed")
 
-		return &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(bytes.NewReader(content)),
-		}, nil
+	d.client = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.String(), "/wireless/scan/status") {
+				scans++
+				return &http.Response{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error", Body: http.NoBody}, nil
+			}
+			return api.RoundTrip(req)
+		}),
 	}
+	d.wirelessScanUpdated = mockNow().Add(-2 * time.Hour)
+	assert.Equal(t, 5, testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect)), "cached results should be served when the refresh fails")
+	assert.Greater(t, scans, 2, "failed refresh should be retried")
+}
 
-	if strings.HasSuffix(req.URL.String(), "/modems/status") {
-		content, err := os.ReadFile("tests/modems_status.json")
-		assert.NoError(m.T, err)
+func TestDevice_CollectWireGuardStatus(t *testing.T) {
+	dir := t.TempDir()
+	status := `{"success": true, "data": [
+		{"name": "wg0", "up": false, "peers": [
+			{"name": "laptop", "latest_handshake": 1747248440, "rx_bytes": 100, "tx_bytes": 200},
+			{"name": "phone", "latest_handshake": 1747240000, "rx_bytes": 10, "tx_bytes": 20}
+		]},
+		{"name": "wg1", "up": true, "peers": [
+			{"name": "notebook", "latest_handshake": 1747248480, "rx_bytes": 1, "tx_bytes": 2}
+		]}
+	]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "wireguard_status.json"), []byte(status), 0o600))
 
-		return &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(bytes.NewReader(content)),
-		}, nil
+	d := testDevice(t, simulatorTransport(t, dir))
+	d.token = "secret_token"
+	d.translator = &Translator{
+		vpn: map[string]string{
+			"wg0":      "office",
+			"wg1":      "office",
+			"notebook": "laptop",
+		},
 	}
 
-	if strings.HasSuffix(req.URL.String(), "/system/device/usage/status") {
-		content, err := os.ReadFile("tests/system_device_usage_status.json")
-		assert.NoError(m.T, err)
+	expected := `
+# HELP teltonika_vpn_peer_last_handshake_age_seconds Seconds since the last WireGuard handshake with the peer
+# TYPE teltonika_vpn_peer_last_handshake_age_seconds gauge
+teltonika_vpn_peer_last_handshake_age_seconds{device="RUT007",peer="laptop",tunnel="office",type="wireguard"} 20
+teltonika_vpn_peer_last_handshake_age_seconds{device="RUT007",peer="phone",tunnel="office",type="wireguard"} 8500
+# HELP teltonika_vpn_peer_received_bytes_total Data received from the VPN peer in bytes
+# TYPE teltonika_vpn_peer_received_bytes_total counter
+teltonika_vpn_peer_received_bytes_total{device="RUT007",peer="laptop",tunnel="office",type="wireguard"} 101
+teltonika_vpn_peer_received_bytes_total{device="RUT007",peer="phone",tunnel="office",type="wireguard"} 10
+# HELP teltonika_vpn_peers_connected Count of connected VPN peers
+# TYPE teltonika_vpn_peers_connected gauge
+teltonika_vpn_peers_connected{device="RUT007",tunnel="office",type="wireguard"} 2
+# HELP teltonika_vpn_up VPN tunnel is up 1/0
+# TYPE teltonika_vpn_up gauge
+teltonika_vpn_up{device="RUT007",tunnel="office",type="wireguard"} 1
+`
+	err := testutil.CollectAndCompare(prometheus.CollectorFunc(d.collectWireGuardStatus), strings.NewReader(expected),
+		"teltonika_vpn_peer_last_handshake_age_seconds", "teltonika_vpn_peer_received_bytes_total",
+		"teltonika_vpn_peers_connected", "teltonika_vpn_up")
+	require.NoError(t, err)
+}
+
+func TestDevice_CollectIpsecStatus(t *testing.T) {
+	dir := t.TempDir()
+	status := `{"success": true, "data": [
+		{"name": "hq_ipsec", "state": "CONNECTING", "remote": "203.0.113.2", "bytes_in": 1, "bytes_out": 2},
+		{"name": "hq_ipsec_backup", "state": "ESTABLISHED", "remote": "203.0.113.3", "bytes_in": 10, "bytes_out": 20}
+	]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ipsec_status.json"), []byte(status), 0o600))
 
-		return &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(bytes.NewReader(content)),
-		}, nil
+	d := testDevice(t, simulatorTransport(t, dir))
+	d.token = "secret_token"
+	d.translator = &Translator{
+		vpn: map[string]string{
+			"hq_ipsec":        "hq",
+			"hq_ipsec_backup": "hq",
+			"203.0.113.2":     "hq-gw",
+			"203.0.113.3":     "hq-gw",
+		},
 	}
 
-	if strings.HasSuffix(req.URL.String(), "/dhcp/leases/ipv4/status") {
-		content, err := os.ReadFile("tests/dhcp_leases_ipv4_status.json")
-		assert.NoError(m.T, err)
+	expected := `
+# HELP teltonika_vpn_peer_received_bytes_total Data received from the VPN peer in bytes
+# TYPE teltonika_vpn_peer_received_bytes_total counter
+teltonika_vpn_peer_received_bytes_total{device="RUT007",peer="hq-gw",tunnel="hq",type="ipsec"} 11
+# HELP teltonika_vpn_peers_connected Count of connected VPN peers
+# TYPE teltonika_vpn_peers_connected gauge
+teltonika_vpn_peers_connected{device="RUT007",tunnel="hq",type="ipsec"} 1
+# HELP teltonika_vpn_up VPN tunnel is up 1/0
+# TYPE teltonika_vpn_up gauge
+teltonika_vpn_up{device="RUT007",tunnel="hq",type="ipsec"} 1
+`
+	err := testutil.CollectAndCompare(prometheus.CollectorFunc(d.collectIpsecStatus), strings.NewReader(expected),
+		"teltonika_vpn_peer_received_bytes_total", "teltonika_vpn_peers_connected", "teltonika_vpn_up")
+	require.NoError(t, err)
+}
+
+func TestDevice_CollectProcessesStatus(t *testing.T) {
+	dir := t.TempDir()
+	status := `{"success": true, "data": [
+		{"pid": 1, "name": "procd", "cpu": 0.5, "memory": 1000},
+		{"pid": 10, "name": "sh", "cpu": 1, "memory": 100},
+		{"pid": 11, "name": "sh", "cpu": 2, "memory": 200}
+	]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "system_processes_status.json"), []byte(status), 0o600))
+
+	d := testDevice(t, simulatorTransport(t, dir))
+	d.token = "secret_token"
 
-		return &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(bytes.NewReader(content)),
-		}, nil
+	d.topProcesses = 1
+	expected := `
+# HELP teltonika_process_cpu_percent CPU usage of the processes with the name in percent
+# TYPE teltonika_process_cpu_percent gauge
+teltonika_process_cpu_percent{device="RUT007",name="procd"} 0.5
+teltonika_process_cpu_percent{device="RUT007",name="sh"} 3
+# HELP teltonika_processes Count of running processes
+# TYPE teltonika_processes gauge
+teltonika_processes{device="RUT007"} 3
+`
+	err := testutil.CollectAndCompare(prometheus.CollectorFunc(d.collectProcessesStatus), strings.NewReader(expected),
+		"teltonika_process_cpu_percent", "teltonika_processes")
+	require.NoError(t, err)
+
+	// 0 disables the per-process series, a negative limit must not panic
+	for _, top := range []int{0, -1} {
+		d.topProcesses = top
+		assert.Equal(t, 1, testutil.CollectAndCount(prometheus.CollectorFunc(d.collectProcessesStatus)))
 	}
+}
 
-	if strings.HasSuffix(req.URL.String(), "/dhcp/leases/ipv6/status") {
-		content, err := os.ReadFile("tests/dhcp_leases_ipv6_status.json")
-		assert.NoError(m.T, err)
+func TestDevice_CollectHotspotSessionsStatus(t *testing.T) {
+	dir := t.TempDir()
+	status := `{"success": true, "data": [
+		{"instance": "hotspot1", "mac": "14:25:36:ab:aa:44", "session_time": 60, "input_octets": 100, "output_octets": 1000},
+		{"instance": "hotspot1", "mac": "14:25:36:ab:aa:44", "session_time": 30, "input_octets": 10, "output_octets": 20},
+		{"instance": "hotspot1", "mac": "14:25:36:ab:aa:55", "session_time": 10, "input_octets": 1, "output_octets": 2},
+		{"instance": "hotspot1", "mac": "14:25:36:ab:aa:66", "session_time": 10, "input_octets": 5, "output_octets": 5}
+	]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hotspot_sessions_status.json"), []byte(status), 0o600))
 
-		return &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(bytes.NewReader(content)),
-		}, nil
+	d := testDevice(t, simulatorTransport(t, dir))
+	d.token = "secret_token"
+	d.translator = &Translator{
+		mac: map[string]string{
+			"14:25:36:AB:AA:44": "iphone",
+			"14:25:36:AB:AA:55": "iphone",
+		},
 	}
 
-	if strings.HasSuffix(req.URL.String(), "/wireless/interfaces/status") {
-		content, err := os.ReadFile("tests/wireless_interfaces_status.json")
-		assert.NoError(m.T, err)
+	expected := `
+# HELP teltonika_hotspot_client_received_bytes_total Data received from the hotspot client in bytes
+# TYPE teltonika_hotspot_client_received_bytes_total counter
+teltonika_hotspot_client_received_bytes_total{client="14:25:36:AB:AA:66",device="RUT007",instance="hotspot1"} 5
+teltonika_hotspot_client_received_bytes_total{client="iphone",device="RUT007",instance="hotspot1"} 111
+`
+	err := testutil.CollectAndCompare(prometheus.CollectorFunc(d.collectHotspotSessionsStatus), strings.NewReader(expected),
+		"teltonika_hotspot_client_received_bytes_total")
+	require.NoError(t, err)
+}
+
+func TestDevice_RecordFailover(t *testing.T) {
+	d := Device{}
+
+	d.recordFailover("wan")  // first observation is not a failover
+	d.recordFailover("wan")  // no change
+	d.recordFailover("mob1") // failover to mobile
+	d.recordFailover("")     // no active interface, keep the last one
+	d.recordFailover("wan")  // back to wan
+	d.recordFailover("mob1")
+
+	assert.Equal(t, map[string]int{"mob1": 2, "wan": 1}, d.failoverEvents)
+}
+
+func TestDevice_RecordPortLink(t *testing.T) {
+	d := Device{}
+
+	d.recordPortLink("LAN1", true)
+	d.recordPortLink("LAN2", false)
+	d.recordPortLink("LAN1", false) // flap
+	d.recordPortLink("LAN2", false)
+	d.recordPortLink("LAN1", true) // flap
+
+	assert.Equal(t, 2, d.portFlaps["LAN1"])
+	assert.Equal(t, 0, d.portFlaps["LAN2"])
+}
+
+func TestDevice_CollectEvents(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "events.jsonl")
+	d := testDevice(t, fixtureTransport(t))
+	d.token = "secret_token"
+	d.events = NewEventForwarder(output)
+	d.eventsCursor = -1
 
-		return &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(bytes.NewReader(content)),
-		}, nil
+	// first read only remembers the cursor
+	assert.Equal(t, 0, testutil.CollectAndCount(prometheus.CollectorFunc(d.collectEventsStatus)))
+	assert.Equal(t, int64(4), d.eventsCursor)
+	assert.NoFileExists(t, output)
+
+	d.eventsCursor = 2 // pretend two events were logged since the last scrape
+	expected := `
+# HELP teltonika_events_total Count of events logged by the device since the exporter start
+# TYPE teltonika_events_total counter
+teltonika_events_total{device="RUT007",severity="warning",type="mobile"} 2
+`
+	err := testutil.CollectAndCompare(prometheus.CollectorFunc(d.collectEventsStatus), strings.NewReader(expected))
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"device":"RUT007","id":3,"time":1747248400,"type":"mobile","severity":"warning","message":"Mobile data disconnected"}`,
+		strings.Split(string(content), "\n")[0])
+	assert.Equal(t, 2, strings.Count(string(content), "\n"))
+}
+
+func TestDevice_CollectDeviceInfoCache(t *testing.T) {
+	api := simulatorTransport(t, "tests", testsOptionalEndpoints...)
+	requests := 0
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.String(), "/system/device/status") {
+			requests++
+		}
+		return api.RoundTrip(req)
+	}))
+
+	assert.Equal(t, 0, testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect)))
+	assert.Equal(t, 0, requests, "device info belongs to the system section")
+
+	d.sections = mustResolveSections(t, DefaultSections, SectionSystem)
+	for range 3 {
+		assert.Equal(t, 1, testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect), "teltonika_device_info"))
 	}
+	assert.Equal(t, 1, requests, "device info should be requested once per login")
 
-	m.T.Errorf("unexpected API call - mock is missing")
-	return nil, fmt.Errorf("no mock")
+	d.token = "" // force new login
+	assert.Equal(t, 1, testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect), "teltonika_device_info"))
+	assert.Equal(t, 2, requests, "device info should be requested again after login")
 }
 
-func mockHttpClient(t *testing.T) *http.Client {
-	t.Helper()
-	return &http.Client{
-		Transport: &RoundTripperMock{T: t},
+func TestDevice_CollectClockOffset(t *testing.T) {
+	// the request starts at 455 and ends at 459, the device reports 461
+	calls := 0
+	d := testDevice(t, fixtureTransport(t), mustResolveSections(t, DefaultSections, SectionSystem)...)
+	d.token = "secret_token"
+	d.now = func() time.Time {
+		calls++
+		if calls%2 == 1 {
+			return time.Unix(1747248455, 0)
+		}
+		return time.Unix(1747248459, 0)
 	}
+
+	expected := `
+# HELP teltonika_device_clock_offset_seconds Difference between the device clock and the exporter clock in seconds
+# TYPE teltonika_device_clock_offset_seconds gauge
+teltonika_device_clock_offset_seconds{device="RUT007"} 4
+`
+	err := testutil.CollectAndCompare(prometheus.CollectorFunc(d.collectSystemDeviceUsageStatus),
+		strings.NewReader(expected), "teltonika_device_clock_offset_seconds")
+	require.NoError(t, err)
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+var discoverCmd = cobra.Command{
+	Use:     "discover",
+	Example: "./teltonika-exporter discover --config path/to/config.yaml",
+	Short:   "Discover sections supported by the configured devices",
+	Long: `Logs in to every configured device, probes the endpoints of all known sections and prints
+a ready-to-paste config snippet with the supported sections of each device.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configFile == "" {
+			return fmt.Errorf("no config file specified")
+		}
+
+		config, err := ParseConfig(configFile)
+		if err != nil {
+			return fmt.Errorf("error parsing config file: %w", err)
+		}
+
+		collector, err := NewCollector(cmd.Context(), config, NewMetrics())
+		if err != nil {
+			return fmt.Errorf("error creating collector: %w", err)
+		}
+
+		failed := 0
+		for _, device := range collector.devices {
+			sections, err := device.Discover(collector.sections.Sections())
+			if err != nil {
+				failed++
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "# %s: %s\n", device.name, strings.ReplaceAll(err.Error(), "\n", "\n# "))
+			}
+
+			if sections != nil {
+				PrintDiscovery(cmd.OutOrStdout(), device, sections)
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("discovery failed for %d device(s)", failed)
+		}
+
+		return nil
+	},
+}
+
+// Discover logs in to the device and returns the candidate sections it supports. Sections which
+// could not be probed are reported in the error, the supported sections are returned anyway.
+func (d *Device) Discover(candidates []Section) ([]Section, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if err := d.authenticate(); err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	if err := d.loadInfo(); err != nil {
+		slog.Warn("failed to get system device status", "device", d.name, "error", err)
+	}
+
+	sections := []Section{}
+	var errs []error
+	for _, probe := range d.probeSections(candidates) {
+		switch {
+		case probe.err != nil:
+			errs = append(errs, fmt.Errorf("section %s could not be probed: %w", probe.section.Name(), probe.err))
+		case probe.supported:
+			sections = append(sections, probe.section)
+		}
+	}
+
+	return sections, errors.Join(errs...)
+}
+
+// refreshSections discovers the sections of a device with collect: auto. Only definite results
+// are kept, sections whose probe failed (e.g. a timeout) are probed again on the next collection.
+// All sections are probed again after a firmware change, as upgrades add and remove API endpoints.
+// When the firmware can not be read, the sections discovered on the previous firmware are kept.
+func (d *Device) refreshSections() {
+	firmware := d.discoveredFirmware
+	if err := d.loadInfo(); err != nil {
+		slog.Error("failed to get system device status, keeping the discovered sections",
+			"device", d.name, "firmware", firmware, "error", err)
+	} else {
+		firmware = d.firmware()
+	}
+
+	if d.discovered == nil || firmware != d.discoveredFirmware {
+		d.discovered = make(map[string]bool)
+		d.discoveredFirmware = firmware
+	}
+
+	var pending []Section
+	for _, section := range d.candidates {
+		if _, ok := d.discovered[section.Name()]; !ok {
+			pending = append(pending, section)
+		}
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	for _, probe := range d.probeSections(pending) {
+		if probe.err != nil {
+			slog.Warn("failed to probe section, retrying on the next collection",
+				"device", d.name, "section", probe.section.Name(), "error", probe.err)
+			continue
+		}
+
+		d.discovered[probe.section.Name()] = probe.supported
+	}
+
+	d.sections = nil
+	var names []string
+	for _, section := range d.candidates {
+		if d.discovered[section.Name()] {
+			d.sections = append(d.sections, section)
+			names = append(names, section.Name())
+		}
+	}
+	slog.Info("discovered sections", "device", d.name, "firmware", firmware, "sections", names)
+}
+
+// sectionProbe is the result of probing a section, err is set when the support could not be determined.
+type sectionProbe struct {
+	section   Section
+	supported bool
+	err       error
+}
+
+// probeSections probes the candidate sections concurrently.
+func (d *Device) probeSections(candidates []Section) []sectionProbe {
+	probes := make([]sectionProbe, len(candidates))
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(candidates))
+	for i, section := range candidates {
+		go func() {
+			defer wg.Done()
+			supported, err := d.supports(section)
+			probes[i] = sectionProbe{section: section, supported: supported, err: err}
+		}()
+	}
+	wg.Wait()
+
+	return probes
+}
+
+// supports reports whether at least one endpoint of the section answers successfully. Responses
+// without the success field (e.g. of custom collector endpoints) count as successful. Missing
+// endpoints and success false mean the section is not supported, other failures are returned
+// when no endpoint succeeded.
+func (d *Device) supports(section Section) (bool, error) {
+	var errs []error
+	for _, endpoint := range section.Endpoints() {
+		var response json.RawMessage
+		err := d.get(endpoint, d.token, &response)
+		switch {
+		case errors.Is(err, ErrEndpointNotFound), errors.Is(err, ErrUnsuccessful):
+			slog.Debug("section endpoint is not available", "device", d.name, "section", section.Name(), "error", err)
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			return true, nil
+		}
+	}
+
+	return false, errors.Join(errs...)
+}
+
+// firmware returns the firmware version of the device, empty when it is not known.
+func (d *Device) firmware() string {
+	if d.info == nil {
+		return ""
+	}
+
+	return d.info.Data.Static.FwVersion
+}
+
+// PrintDiscovery writes a config snippet of the device with the discovered sections. The credentials
+// are placeholders, the snippet never contains the configured password.
+func PrintDiscovery(w io.Writer, d *Device, sections []Section) {
+	names := make([]string, len(sections))
+	for i, section := range sections {
+		names[i] = strconv.Quote(section.Name())
+	}
+
+	if d.info != nil {
+		_, _ = fmt.Fprintf(w, "# %s, firmware %s\n", d.info.Data.Static.Model, d.info.Data.Static.FwVersion)
+	}
+	_, _ = fmt.Fprintf(w, "- name: %q\n", d.name)
+	_, _ = fmt.Fprintf(w, "  host: %q\n", d.host)
+	_, _ = fmt.Fprintf(w, "  username: %q\n", "<username>")
+	_, _ = fmt.Fprintf(w, "  password: %q\n", "<password>")
+	_, _ = fmt.Fprintf(w, "  collect: [ %s ]\n", strings.Join(names, ", "))
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unsupportedRoundTripper answers 404 for the modem and I/O endpoints, like a router without them.
+func unsupportedRoundTripper(t *testing.T, probes *int) roundTripperFunc {
+	api := simulatorTransport(t, "tests", testsOptionalEndpoints...)
+	mtx := sync.Mutex{}
+	return func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/modems/status") {
+			mtx.Lock()
+			*probes++
+			mtx.Unlock()
+		}
+
+		if strings.HasSuffix(req.URL.Path, "/modems/status") || strings.HasSuffix(req.URL.Path, "/io/status") {
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       io.NopCloser(strings.NewReader(`{"success":false}`)),
+			}, nil
+		}
+
+		return api.RoundTrip(req)
+	}
+}
+
+func TestDevice_Discover(t *testing.T) {
+	probes := 0
+	d := testDevice(t, unsupportedRoundTripper(t, &probes))
+	d.host = "192.168.1.1"
+
+	candidates := mustResolveSections(t, DefaultSections, SectionSystem, SectionModem, SectionIo, SectionServices)
+	sections, err := d.Discover(candidates)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	PrintDiscovery(&out, d, sections)
+	assert.Equal(t, `# RUTX50, firmware RUTX_R_00.07.13.1
+- name: "RUT007"
+  host: "192.168.1.1"
+  username: "<username>"
+  password: "<password>"
+  collect: [ "system", "services" ]
+`, out.String())
+}
+
+func TestDevice_CollectAuto(t *testing.T) {
+	probes := 0
+	d := testDevice(t, unsupportedRoundTripper(t, &probes))
+	d.candidates = mustResolveSections(t, DefaultSections, SectionSystem, SectionModem)
+
+	collect := func() {
+		ch := make(chan prometheus.Metric, 100)
+		d.Collect(ch)
+		close(ch)
+	}
+
+	collect()
+	assert.Equal(t, 1, probes)
+	require.Len(t, d.sections, 1)
+	assert.Equal(t, SectionSystem, d.sections[0].Name())
+
+	collect()
+	assert.Equal(t, 1, probes, "sections are not probed again on the same firmware")
+
+	d.info.Data.Static.FwVersion = "RUTX_R_00.07.14"
+	collect()
+	assert.Equal(t, 2, probes, "sections are probed again after a firmware upgrade")
+}
+
+func TestDevice_CollectAutoKeepsSectionsWithoutFirmware(t *testing.T) {
+	probes := 0
+	unsupported := unsupportedRoundTripper(t, &probes)
+	failing := false
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if failing && strings.HasSuffix(req.URL.Path, "/system/device/status") {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Body: http.NoBody, Header: http.Header{}}, nil
+		}
+		return unsupported.RoundTrip(req)
+	}))
+	d.candidates = mustResolveSections(t, DefaultSections, SectionSystem, SectionModem)
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	require.NoError(t, d.authenticate())
+	d.refreshSections()
+	assert.Equal(t, 1, probes)
+
+	failing = true
+	d.info = nil
+	d.refreshSections()
+	assert.Equal(t, 1, probes, "sections are not probed again when the firmware is unknown")
+	assert.Equal(t, "RUTX_R_00.07.13.1", d.discoveredFirmware)
+	require.Len(t, d.sections, 1)
+
+	failing = false
+	d.refreshSections()
+	assert.Equal(t, 1, probes, "sections are not probed again once the same firmware is read")
+}
+
+func TestDevice_CollectAutoRetriesFailedProbes(t *testing.T) {
+	api := simulatorTransport(t, "tests", testsOptionalEndpoints...)
+	failing := true
+	probes := 0
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/modems/status") {
+			probes++
+			if failing {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Body: http.NoBody, Header: http.Header{}}, nil
+			}
+		}
+		return api.RoundTrip(req)
+	}))
+	d.candidates = mustResolveSections(t, DefaultSections, SectionSystem, SectionModem)
+
+	d.mtx.Lock()
+	require.NoError(t, d.authenticate())
+	d.refreshSections()
+	assert.Equal(t, 1, probes)
+	require.Len(t, d.sections, 1, "a failed probe does not add the section")
+
+	failing = false
+	d.refreshSections()
+	assert.Equal(t, 2, probes, "a failed probe is retried")
+	require.Len(t, d.sections, 2)
+	assert.Equal(t, SectionModem, d.sections[1].Name())
+
+	d.refreshSections()
+	assert.Equal(t, 2, probes, "a successful probe is kept")
+	d.mtx.Unlock()
+}
+
+func TestDevice_SupportsCustomCollector(t *testing.T) {
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"data": {"value": 1}}`)),
+		}, nil
+	}))
+	d.token = "secret_token"
+
+	collector := NewCustomCollectors([]CustomCollectorConfig{{Name: "custom", Endpoint: "/custom/status"}})[0]
+	supported, err := d.supports(collector)
+	require.NoError(t, err)
+	assert.True(t, supported, "endpoints without the success field are supported")
+}
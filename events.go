@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ForwardedEvent is a single line written by the EventForwarder.
+type ForwardedEvent struct {
+	Device   string `json:"device"`
+	ID       int64  `json:"id"`
+	Time     int64  `json:"time"`
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// EventForwarder writes router events as JSON lines to a file or to the standard output.
+// The file is opened for every batch of events, so it plays well with logrotate.
+type EventForwarder struct {
+	path string
+	mtx  sync.Mutex
+}
+
+func NewEventForwarder(path string) *EventForwarder {
+	return &EventForwarder{path: path}
+}
+
+func (f *EventForwarder) Forward(events []ForwardedEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.path == "-" {
+		_, err := io.Copy(os.Stdout, &buf)
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to open events file: %w", err)
+	}
+
+	if _, err := buf.WriteTo(file); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write events: %w", err)
+	}
+
+	return file.Close()
+}
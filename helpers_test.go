@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testDevice returns a device named RUT007 sending its requests through the transport. Tests set
+// the other fields they need, e.g. the token to call the collect functions without a login.
+func testDevice(t *testing.T, transport http.RoundTripper, sections ...Section) *Device {
+	t.Helper()
+
+	return &Device{
+		name:       "RUT007",
+		schema:     "https",
+		host:       "localhost",
+		sections:   sections,
+		client:     &http.Client{Transport: transport},
+		metrics:    NewMetrics(),
+		translator: &Translator{},
+		now:        mockNow,
+
+		ctx: t.Context(),
+		mtx: sync.Mutex{},
+	}
+}
+
+func mustResolveSections(t *testing.T, registry *SectionRegistry, names ...string) []Section {
+	t.Helper()
+	sections, err := registry.Resolve(names)
+	require.NoError(t, err)
+	return sections
+}
+
+// mockNow returns a fixed time shortly after the fixtures were captured.
+func mockNow() time.Time {
+	return time.Unix(1747248500, 0)
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// testsOptionalEndpoints have no fixture in tests, the recorded router does not have Samba installed.
+var testsOptionalEndpoints = []string{"/samba/status"}
+
+// simulatorTransport returns a transport sending every request to an in-process simulator
+// replaying the fixture directory, whatever the device host is. The transport is strict: a request
+// without a fixture fails the test, unless its endpoint is one of the optional endpoints, which
+// are answered with 404 like on a router without the feature.
+func simulatorTransport(t *testing.T, dir string, optional ...string) http.RoundTripper {
+	t.Helper()
+	server := httptest.NewTLSServer(NewSimulator(SimulatorOptions{Dir: dir}))
+	t.Cleanup(server.Close)
+
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		endpoint := strings.TrimPrefix(req.URL.Path, "/api")
+		if _, err := os.Stat(filepath.Join(dir, FixtureFile(endpoint))); err != nil && !slices.Contains(optional, endpoint) {
+			t.Errorf("unexpected API call %s - fixture is missing", endpoint)
+		}
+
+		return transport.RoundTrip(req)
+	})
+}
+
+// fixtureTransport returns a simulator transport replaying the fixtures in tests.
+func fixtureTransport(t *testing.T) http.RoundTripper {
+	t.Helper()
+	return simulatorTransport(t, "tests", testsOptionalEndpoints...)
+}
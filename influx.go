@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// InfluxConfig enables pushing of the metrics to an InfluxDB (or Telegraf) write endpoint.
+type InfluxConfig struct {
+	URL      string        `yaml:"url"`                // write URL, e.g. http://influxdb:8086/api/v2/write?org=noc&bucket=teltonika
+	Token    string        `yaml:"token,omitempty"`    // sent as "Authorization: Token <token>"
+	Interval time.Duration `yaml:"interval,omitempty"` // push interval
+}
+
+// Validate checks the influx block of the config file.
+func (c *InfluxConfig) Validate() error {
+	var errs []error
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, errors.New("url must be an absolute http or https URL"))
+	}
+
+	if c.Interval < 0 {
+		errs = append(errs, errors.New("interval must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// WriteLineProtocol writes the metric families in InfluxDB line protocol. Like the Telegraf prometheus
+// parser, every metric is a measurement with labels as tags and a gauge, counter or value field.
+// Histograms have count, sum and a field per bucket upper bound.
+func WriteLineProtocol(w io.Writer, families []*dto.MetricFamily, timestamp time.Time) error {
+	ts := strconv.FormatInt(timestamp.UnixNano(), 10)
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			var fields []string
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				fields = appendField(fields, "gauge", metric.GetGauge().GetValue())
+			case dto.MetricType_COUNTER:
+				fields = appendField(fields, "counter", metric.GetCounter().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				fields = appendField(fields, "count", float64(histogram.GetSampleCount()))
+				fields = appendField(fields, "sum", histogram.GetSampleSum())
+				for _, bucket := range histogram.GetBucket() {
+					fields = appendField(fields, strconv.FormatFloat(bucket.GetUpperBound(), 'f', -1, 64), float64(bucket.GetCumulativeCount()))
+				}
+			default:
+				fields = appendField(fields, "value", metric.GetUntyped().GetValue())
+			}
+
+			if len(fields) == 0 {
+				continue
+			}
+
+			var line strings.Builder
+			line.WriteString(influxEscape(family.GetName(), ", "))
+			for _, label := range metric.GetLabel() {
+				if label.GetValue() == "" {
+					continue // empty tag values are not allowed
+				}
+				line.WriteString("," + influxEscape(label.GetName(), ",= ") + "=" + influxEscape(label.GetValue(), ",= "))
+			}
+			line.WriteString(" " + strings.Join(fields, ",") + " " + ts + "\n")
+
+			if _, err := io.WriteString(w, line.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// appendField adds the field unless the value is not representable in line protocol.
+func appendField(fields []string, key string, value float64) []string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fields
+	}
+
+	return append(fields, influxEscape(key, ",= ")+"="+strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// influxEscape escapes the special characters with a backslash.
+func influxEscape(s, special string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	for _, c := range special {
+		s = strings.ReplaceAll(s, string(c), `\`+string(c))
+	}
+
+	return s
+}
+
+// gatherLineProtocol collects the metrics of the gatherer in line protocol.
+func gatherLineProtocol(gatherer prometheus.Gatherer) ([]byte, error) {
+	families, err := gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return nil, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	if err != nil {
+		slog.Warn("metrics gathered with errors", "error", err)
+	}
+
+	var body bytes.Buffer
+	if err := WriteLineProtocol(&body, families, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return body.Bytes(), nil
+}
+
+// InfluxHandler serves the metrics of the gatherer in line protocol.
+func InfluxHandler(gatherer prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := gatherLineProtocol(gatherer)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(body)
+	})
+}
+
+// InfluxPusher periodically writes the metrics of the gatherer to an InfluxDB write endpoint.
+type InfluxPusher struct {
+	config   InfluxConfig
+	gatherer prometheus.Gatherer
+	client   *http.Client
+}
+
+func NewInfluxPusher(config InfluxConfig, gatherer prometheus.Gatherer) *InfluxPusher {
+	return &InfluxPusher{
+		config:   config,
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run pushes the metrics every interval until the context is cancelled.
+func (p *InfluxPusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Push(ctx); err != nil {
+			slog.Error("failed to push metrics to influx", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Push collects the metrics once and writes them to the endpoint.
+func (p *InfluxPusher) Push(ctx context.Context) error {
+	body, err := gatherLineProtocol(p.gatherer)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	request.Header.Set("User-Agent", "Teltonika Exporter")
+	if p.config.Token != "" {
+		request.Header.Set("Authorization", "Token "+p.config.Token)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("influx write request failed: %w", err)
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			slog.Error("failed to close httpResponse body", "error", err)
+		}
+	}()
+
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("influx write failed: %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
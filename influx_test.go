@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func influxTestRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+
+	device := testDevice(t, fixtureTransport(t), mustResolveSections(t, DefaultSections, SectionSystem, SectionModem, SectionHotspot)...)
+	device.cardinalityLimit = 100
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&Collector{
+		metrics:  device.metrics,
+		sections: DefaultSections,
+		devices:  []*Device{device},
+	})
+
+	return registry
+}
+
+func TestWriteLineProtocol(t *testing.T) {
+	families, err := influxTestRegistry(t).Gather()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteLineProtocol(&out, families, time.Unix(1747248500, 0)))
+
+	assert.Contains(t, out.String(), "teltonika_mobile_signal_strength,device=RUT007,sim=2-1 gauge=-56 1747248500000000000\n")
+	assert.Contains(t, out.String(), "teltonika_hotspot_session_max_duration_seconds,device=RUT007,instance=hotspot1 gauge=9000 1747248500000000000\n")
+}
+
+func TestWriteLineProtocol_Histogram(t *testing.T) {
+	families := []*dto.MetricFamily{{
+		Name: proto.String("teltonika_request_duration_seconds"),
+		Type: dto.MetricType_HISTOGRAM.Enum(),
+		Metric: []*dto.Metric{{
+			Label: []*dto.LabelPair{{Name: proto.String("device"), Value: proto.String("RUT007")}},
+			Histogram: &dto.Histogram{
+				SampleCount: proto.Uint64(3),
+				SampleSum:   proto.Float64(4.5),
+				Bucket: []*dto.Bucket{
+					{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(1)},
+					{UpperBound: proto.Float64(5), CumulativeCount: proto.Uint64(3)},
+				},
+			},
+		}},
+	}}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteLineProtocol(&out, families, time.Unix(1747248500, 0)))
+	assert.Equal(t, "teltonika_request_duration_seconds,device=RUT007 count=3,sum=4.5,1=1,5=3 1747248500000000000\n", out.String())
+}
+
+func TestInfluxEscape(t *testing.T) {
+	assert.Equal(t, `Operator\ A\,B\=C`, influxEscape("Operator A,B=C", ",= "))
+	assert.Equal(t, `path\\to`, influxEscape(`path\to`, ",= "))
+}
+
+func TestInfluxPusher_Push(t *testing.T) {
+	var body []byte
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	pusher := NewInfluxPusher(InfluxConfig{URL: server.URL + "/api/v2/write", Token: "secret"}, influxTestRegistry(t))
+	require.NoError(t, pusher.Push(t.Context()))
+
+	assert.Equal(t, "Token secret", authorization)
+	assert.Contains(t, string(body), "teltonika_mobile_signal_strength,device=RUT007,sim=2-1 gauge=-56 ")
+}
+
+func TestInfluxPusher_PushFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bucket not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	pusher := NewInfluxPusher(InfluxConfig{URL: server.URL}, influxTestRegistry(t))
+	assert.EqualError(t, pusher.Push(t.Context()), "influx write failed: 404 Not Found: bucket not found")
+}
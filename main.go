@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
@@ -45,7 +46,10 @@ var root = cobra.Command{
 		}
 
 		metrics := NewMetrics()
-		teltonikaCollector := NewCollector(ctx, config, metrics)
+		teltonikaCollector, err := NewCollector(ctx, config, metrics)
+		if err != nil {
+			return fmt.Errorf("error creating collector: %w", err)
+		}
 
 		registry := prometheus.NewRegistry()
 		registry.MustRegister(teltonikaCollector)
@@ -57,16 +61,65 @@ var root = cobra.Command{
 			<body>
 				<h1>Teltonika exporter</h1>
 				<p><a href="/metrics">Metrics</a></p>
+				<p><a href="/influx">InfluxDB line protocol</a></p>
+				<p><a href="/api/v1/devices">Device status</a></p>
 			</body>
 			</html>`))
 		})
 
-		http.Handle("/metrics", promhttp.HandlerFor(registry,
-			promhttp.HandlerOpts{
-				EnableOpenMetrics: true,
-				Registry:          registry,
-			}),
-		)
+		if config.Otlp == nil || !config.Otlp.DisableMetricsEndpoint {
+			http.Handle("/metrics", promhttp.HandlerFor(registry,
+				promhttp.HandlerOpts{
+					EnableOpenMetrics: true,
+					Registry:          registry,
+				}),
+			)
+		}
+
+		http.Handle("/influx", InfluxHandler(registry))
+		http.Handle("/api/v1/", NewStatusApi(teltonikaCollector))
+
+		// the push exporters share one collection per interval, the cache expires halfway
+		// to the next push so every push gets fresh metrics
+		var intervals []time.Duration
+		if config.Influx != nil {
+			intervals = append(intervals, config.Influx.Interval)
+		}
+		if config.Mqtt != nil {
+			intervals = append(intervals, config.Mqtt.Interval)
+		}
+		if config.Otlp != nil {
+			intervals = append(intervals, config.Otlp.Interval)
+		}
+		var pushGatherer prometheus.Gatherer = registry
+		if len(intervals) > 0 {
+			pushGatherer = NewCachedGatherer(registry, slices.Min(intervals)/2)
+		}
+
+		if config.Influx != nil {
+			go NewInfluxPusher(*config.Influx, pushGatherer).Run(ctx)
+		}
+
+		if config.Mqtt != nil {
+			publisher := NewMqttPublisher(*config.Mqtt, pushGatherer)
+			if err := publisher.Connect(); err != nil {
+				return err
+			}
+			go publisher.Run(ctx)
+		}
+
+		if config.Otlp != nil {
+			attributes := make(map[string]map[string]string)
+			for _, device := range config.Devices {
+				attributes[device.Name] = device.Attributes
+			}
+
+			exporter, err := NewOtlpExporter(ctx, *config.Otlp, pushGatherer, attributes)
+			if err != nil {
+				return err
+			}
+			go exporter.Run(ctx)
+		}
 
 		srv := &http.Server{
 			Addr:              fmt.Sprintf(":%d", port),
@@ -90,12 +143,34 @@ var root = cobra.Command{
 }
 
 func init() {
-	root.Flags().StringVarP(&configFile, "config", "c", "", "Config file path")
+	root.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Config file path")
 	root.Flags().IntVar(&port, "port", 15741, "Exporter port")
+
+	root.AddCommand(&checkConfigCmd)
+	root.AddCommand(&testCmd)
+	root.AddCommand(&discoverCmd)
+
+	recordCmd.Flags().StringVarP(&recordOutput, "output", "o", "fixtures", "Fixture output directory")
+	root.AddCommand(&recordCmd)
+
+	simulateCmd.Flags().StringVar(&simulatorOptions.Dir, "fixtures", "tests", "Fixture directory")
+	simulateCmd.Flags().StringVar(&simulatorListen, "listen", ":8080", "Listen address")
+	simulateCmd.Flags().BoolVar(&simulatorOptions.Animate, "animate", false, "Animate signal values, counters and clients")
+	simulateCmd.Flags().Float64Var(&simulatorOptions.FailureRate, "failure-rate", 0, "Share of requests failing with 500")
+	simulateCmd.Flags().DurationVar(&simulatorOptions.Latency, "latency", 0, "Max random latency added to responses")
+	simulateCmd.Flags().StringVar(&simulatorTlsCert, "tls-cert", "", "TLS certificate file, serves HTTPS when set")
+	simulateCmd.Flags().StringVar(&simulatorTlsKey, "tls-key", "", "TLS key file")
+	root.AddCommand(&simulateCmd)
 }
 
 func main() {
 	if err := root.Execute(); err != nil {
 		slog.Error("Error executing command", slog.String("error", err.Error()))
+
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+		os.Exit(1)
 	}
 }
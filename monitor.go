@@ -6,9 +6,18 @@ type Metrics map[string]*prometheus.Desc
 
 func NewMetrics() Metrics {
 	generalLabels := []string{"device"}
+	serviceLabels := []string{"device", "service", "instance"}
+	hotspotLabels := []string{"device", "instance"}
+	hotspotClientLabels := []string{"device", "instance", "client"}
+	processLabels := []string{"device", "name"}
 	mobileLabels := []string{"device", "sim"}
 	wirelessClientLabels := []string{"device", "client", "radio"}
 	wirelessDeviceLabels := []string{"device", "interface", "radio"}
+	portLabels := []string{"device", "port"}
+	failoverLabels := []string{"device", "interface"}
+	vpnTunnelLabels := []string{"device", "type", "tunnel"}
+	vpnPeerLabels := []string{"device", "type", "tunnel", "peer"}
+	wirelessScanLabels := []string{"device", "radio", "bssid", "ssid", "channel", "encryption"}
 
 	return map[string]*prometheus.Desc{
 		"teltonika_device_uptime": prometheus.NewDesc(
@@ -18,13 +27,83 @@ func NewMetrics() Metrics {
 			nil,
 		),
 
+		"teltonika_device_info": prometheus.NewDesc(
+			"teltonika_device_info",
+			"Device model, serial number and firmware version",
+			[]string{"device", "model", "product_code", "serial", "firmware", "hostname", "batch"},
+			nil,
+		),
+
+		"teltonika_device_boot_time_seconds": prometheus.NewDesc(
+			"teltonika_device_boot_time_seconds",
+			"Device boot time in unix time",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_device_clock_offset_seconds": prometheus.NewDesc(
+			"teltonika_device_clock_offset_seconds",
+			"Difference between the device clock and the exporter clock in seconds",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_ntp_enabled": prometheus.NewDesc(
+			"teltonika_ntp_enabled",
+			"NTP client is enabled 1/0",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_ntp_synchronized": prometheus.NewDesc(
+			"teltonika_ntp_synchronized",
+			"Device clock is synchronized by NTP 1/0",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_ntp_last_sync_timestamp_seconds": prometheus.NewDesc(
+			"teltonika_ntp_last_sync_timestamp_seconds",
+			"Time of the last successful NTP synchronization in unix time",
+			generalLabels,
+			nil,
+		),
+
 		"teltonika_cpu_usage": prometheus.NewDesc(
 			"teltonika_cpu_usage",
-			"CPU usage over 1 minute",
+			"CPU load average over 1 minute, see teltonika_cpu_core_usage_percent for CPU utilisation",
 			generalLabels,
 			nil,
 		),
 
+		"teltonika_cpu_core_usage_percent": prometheus.NewDesc(
+			"teltonika_cpu_core_usage_percent",
+			"Time spent by the CPU core in the mode in percent",
+			[]string{"device", "core", "mode"},
+			nil,
+		),
+
+		"teltonika_processes": prometheus.NewDesc(
+			"teltonika_processes",
+			"Count of running processes",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_process_cpu_percent": prometheus.NewDesc(
+			"teltonika_process_cpu_percent",
+			"CPU usage of the processes with the name in percent",
+			processLabels,
+			nil,
+		),
+
+		"teltonika_process_memory_bytes": prometheus.NewDesc(
+			"teltonika_process_memory_bytes",
+			"Memory used by the processes with the name in bytes",
+			processLabels,
+			nil,
+		),
+
 		"teltonika_load_min_1": prometheus.NewDesc(
 			"teltonika_load_min_1",
 			"CPU load average over the last minute",
@@ -49,28 +128,49 @@ func NewMetrics() Metrics {
 		//nolint:promlinter
 		"teltonika_ram_total": prometheus.NewDesc(
 			"teltonika_ram_total",
-			"Total amount of system memory",
+			"Total amount of system memory in bytes",
 			generalLabels,
 			nil,
 		),
 
 		"teltonika_ram_used": prometheus.NewDesc(
 			"teltonika_ram_used",
-			"Amount of used system memory",
+			"Amount of used system memory in bytes",
 			generalLabels,
 			nil,
 		),
 
 		"teltonika_ram_free": prometheus.NewDesc(
 			"teltonika_ram_free",
-			"Amount of free system memory",
+			"Amount of free system memory in bytes",
 			generalLabels,
 			nil,
 		),
 
 		"teltonika_ram_buffered": prometheus.NewDesc(
 			"teltonika_ram_buffered",
-			"Amount of buffered system memory",
+			"Amount of buffered system memory in bytes",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_ram_shared": prometheus.NewDesc(
+			"teltonika_ram_shared",
+			"Amount of shared system memory in bytes",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_ram_available": prometheus.NewDesc(
+			"teltonika_ram_available",
+			"Amount of system memory available for new processes (free + buffered) in bytes",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_ram_used_percent": prometheus.NewDesc(
+			"teltonika_ram_used_percent",
+			"Used system memory in percent",
 			generalLabels,
 			nil,
 		),
@@ -78,25 +178,46 @@ func NewMetrics() Metrics {
 		//nolint:promlinter
 		"teltonika_flash_total": prometheus.NewDesc(
 			"teltonika_flash_total",
-			"Total amount of flash memory",
+			"Total amount of flash memory in bytes",
 			generalLabels,
 			nil,
 		),
 
 		"teltonika_flash_used": prometheus.NewDesc(
 			"teltonika_flash_used",
-			"Amount of used flash memory",
+			"Amount of used flash memory in bytes",
 			generalLabels,
 			nil,
 		),
 
 		"teltonika_flash_free": prometheus.NewDesc(
 			"teltonika_flash_free",
-			"Amount of free flash memory",
+			"Amount of free flash memory in bytes",
 			generalLabels,
 			nil,
 		),
 
+		"teltonika_flash_used_percent": prometheus.NewDesc(
+			"teltonika_flash_used_percent",
+			"Used flash memory in percent",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_io_state": prometheus.NewDesc(
+			"teltonika_io_state",
+			"State of the digital input, output or relay 1/0",
+			[]string{"device", "pin", "name", "type"},
+			nil,
+		),
+
+		"teltonika_io_analog_value": prometheus.NewDesc(
+			"teltonika_io_analog_value",
+			"Value measured on the analog input",
+			[]string{"device", "pin", "name", "unit"},
+			nil,
+		),
+
 		"teltonika_dhcp_leases_ipv4": prometheus.NewDesc(
 			"teltonika_dhcp_leases_ipv4",
 			"Count of active DHCP IPv4 leases",
@@ -167,6 +288,27 @@ func NewMetrics() Metrics {
 			nil,
 		),
 
+		"teltonika_sms_messages": prometheus.NewDesc(
+			"teltonika_sms_messages",
+			"Count of SMS messages by status (stored, unread, sent, failed)",
+			[]string{"device", "sim", "status"},
+			nil,
+		),
+
+		"teltonika_sms_storage_used": prometheus.NewDesc(
+			"teltonika_sms_storage_used",
+			"Count of SMS messages stored on the SIM card",
+			mobileLabels,
+			nil,
+		),
+
+		"teltonika_sms_storage_total": prometheus.NewDesc(
+			"teltonika_sms_storage_total",
+			"SMS storage capacity of the SIM card",
+			mobileLabels,
+			nil,
+		),
+
 		"teltonika_wireless_device_quality": prometheus.NewDesc(
 			"teltonika_wireless_device_quality",
 			"Wireless device quality",
@@ -250,5 +392,250 @@ func NewMetrics() Metrics {
 			wirelessClientLabels,
 			nil,
 		),
+
+		"teltonika_wireless_scan_networks": prometheus.NewDesc(
+			"teltonika_wireless_scan_networks",
+			"Count of neighbouring wireless networks found by the last scan",
+			[]string{"device", "radio"},
+			nil,
+		),
+
+		"teltonika_wireless_scan_signal": prometheus.NewDesc(
+			"teltonika_wireless_scan_signal",
+			"Signal strength of a neighbouring wireless network in dBm",
+			wirelessScanLabels,
+			nil,
+		),
+
+		"teltonika_vpn_up": prometheus.NewDesc(
+			"teltonika_vpn_up",
+			"VPN tunnel is up 1/0",
+			vpnTunnelLabels,
+			nil,
+		),
+
+		"teltonika_vpn_peers_connected": prometheus.NewDesc(
+			"teltonika_vpn_peers_connected",
+			"Count of connected VPN peers",
+			vpnTunnelLabels,
+			nil,
+		),
+
+		"teltonika_vpn_peer_last_handshake_age_seconds": prometheus.NewDesc(
+			"teltonika_vpn_peer_last_handshake_age_seconds",
+			"Seconds since the last WireGuard handshake with the peer",
+			vpnPeerLabels,
+			nil,
+		),
+
+		"teltonika_vpn_peer_received_bytes_total": prometheus.NewDesc(
+			"teltonika_vpn_peer_received_bytes_total",
+			"Data received from the VPN peer in bytes",
+			vpnPeerLabels,
+			nil,
+		),
+
+		"teltonika_vpn_peer_sent_bytes_total": prometheus.NewDesc(
+			"teltonika_vpn_peer_sent_bytes_total",
+			"Data sent to the VPN peer in bytes",
+			vpnPeerLabels,
+			nil,
+		),
+
+		"teltonika_failover_interface_active": prometheus.NewDesc(
+			"teltonika_failover_interface_active",
+			"WAN interface is currently used for traffic 1/0",
+			failoverLabels,
+			nil,
+		),
+
+		"teltonika_failover_interface_enabled": prometheus.NewDesc(
+			"teltonika_failover_interface_enabled",
+			"WAN interface is enabled in failover configuration 1/0",
+			failoverLabels,
+			nil,
+		),
+
+		"teltonika_failover_interface_online": prometheus.NewDesc(
+			"teltonika_failover_interface_online",
+			"WAN interface is online 1/0",
+			failoverLabels,
+			nil,
+		),
+
+		"teltonika_failover_track_up": prometheus.NewDesc(
+			"teltonika_failover_track_up",
+			"WAN interface passes connection tracking 1/0",
+			failoverLabels,
+			nil,
+		),
+
+		"teltonika_failover_track_hosts_up": prometheus.NewDesc(
+			"teltonika_failover_track_hosts_up",
+			"Count of tracked hosts reachable over the WAN interface",
+			failoverLabels,
+			nil,
+		),
+
+		"teltonika_failover_ping_latency_seconds": prometheus.NewDesc(
+			"teltonika_failover_ping_latency_seconds",
+			"Latency of the tracking ping over the WAN interface in seconds",
+			failoverLabels,
+			nil,
+		),
+
+		"teltonika_failover_ping_loss_percent": prometheus.NewDesc(
+			"teltonika_failover_ping_loss_percent",
+			"Packet loss of the tracking ping over the WAN interface in percent",
+			failoverLabels,
+			nil,
+		),
+
+		"teltonika_failover_events_total": prometheus.NewDesc(
+			"teltonika_failover_events_total",
+			"Count of failovers to the WAN interface observed by the exporter",
+			failoverLabels,
+			nil,
+		),
+
+		"teltonika_port_up": prometheus.NewDesc(
+			"teltonika_port_up",
+			"Ethernet port link is up 1/0",
+			portLabels,
+			nil,
+		),
+
+		"teltonika_port_speed_bits_per_second": prometheus.NewDesc(
+			"teltonika_port_speed_bits_per_second",
+			"Negotiated Ethernet port speed in bits per second",
+			portLabels,
+			nil,
+		),
+
+		"teltonika_port_full_duplex": prometheus.NewDesc(
+			"teltonika_port_full_duplex",
+			"Ethernet port runs in full duplex 1/0",
+			portLabels,
+			nil,
+		),
+
+		"teltonika_port_poe_enabled": prometheus.NewDesc(
+			"teltonika_port_poe_enabled",
+			"PoE is enabled on the Ethernet port 1/0",
+			portLabels,
+			nil,
+		),
+
+		"teltonika_port_poe_power_watts": prometheus.NewDesc(
+			"teltonika_port_poe_power_watts",
+			"Power drawn by the PoE device in watts",
+			portLabels,
+			nil,
+		),
+
+		"teltonika_port_link_flaps_total": prometheus.NewDesc(
+			"teltonika_port_link_flaps_total",
+			"Count of Ethernet port link state changes observed by the exporter",
+			portLabels,
+			nil,
+		),
+
+		"teltonika_events_total": prometheus.NewDesc(
+			"teltonika_events_total",
+			"Count of events logged by the device since the exporter start",
+			[]string{"device", "type", "severity"},
+			nil,
+		),
+
+		"teltonika_rms_info": prometheus.NewDesc(
+			"teltonika_rms_info",
+			"Configured Remote Management System server",
+			[]string{"device", "hostname", "port"},
+			nil,
+		),
+
+		"teltonika_rms_enabled": prometheus.NewDesc(
+			"teltonika_rms_enabled",
+			"Remote Management System connection is enabled 1/0",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_rms_connected": prometheus.NewDesc(
+			"teltonika_rms_connected",
+			"Device is connected to Remote Management System 1/0",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_rms_last_connection_timestamp_seconds": prometheus.NewDesc(
+			"teltonika_rms_last_connection_timestamp_seconds",
+			"Time of the last connection to Remote Management System in unix time",
+			generalLabels,
+			nil,
+		),
+
+		"teltonika_service_enabled": prometheus.NewDesc(
+			"teltonika_service_enabled",
+			"Service is enabled 1/0",
+			serviceLabels,
+			nil,
+		),
+
+		"teltonika_service_running": prometheus.NewDesc(
+			"teltonika_service_running",
+			"Service is running or its last operation succeeded 1/0",
+			serviceLabels,
+			nil,
+		),
+
+		"teltonika_service_last_update_timestamp_seconds": prometheus.NewDesc(
+			"teltonika_service_last_update_timestamp_seconds",
+			"Time of the last service update, e.g. DDNS record update, in unix time",
+			serviceLabels,
+			nil,
+		),
+
+		"teltonika_hotspot_sessions": prometheus.NewDesc(
+			"teltonika_hotspot_sessions",
+			"Count of active hotspot sessions",
+			hotspotLabels,
+			nil,
+		),
+
+		"teltonika_hotspot_authenticated_users": prometheus.NewDesc(
+			"teltonika_hotspot_authenticated_users",
+			"Count of authenticated hotspot users",
+			hotspotLabels,
+			nil,
+		),
+
+		"teltonika_hotspot_session_max_duration_seconds": prometheus.NewDesc(
+			"teltonika_hotspot_session_max_duration_seconds",
+			"Duration of the longest active hotspot session in seconds",
+			hotspotLabels,
+			nil,
+		),
+
+		"teltonika_hotspot_session_average_duration_seconds": prometheus.NewDesc(
+			"teltonika_hotspot_session_average_duration_seconds",
+			"Average duration of the active hotspot sessions in seconds",
+			hotspotLabels,
+			nil,
+		),
+
+		"teltonika_hotspot_client_received_bytes_total": prometheus.NewDesc(
+			"teltonika_hotspot_client_received_bytes_total",
+			"Data received from the hotspot client in bytes",
+			hotspotClientLabels,
+			nil,
+		),
+
+		"teltonika_hotspot_client_sent_bytes_total": prometheus.NewDesc(
+			"teltonika_hotspot_client_sent_bytes_total",
+			"Data sent to the hotspot client in bytes",
+			hotspotClientLabels,
+			nil,
+		),
 	}
 }
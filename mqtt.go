@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Formats of the published MQTT messages.
+const (
+	MqttFormatJson   = "json"   // one JSON document per device
+	MqttFormatTopics = "topics" // one topic per metric, e.g. teltonika/<device>/mobile/<sim>/rsrp
+)
+
+// MqttConfig enables publishing of the metrics to an MQTT broker after every collection.
+type MqttConfig struct {
+	Broker   string        `yaml:"broker"` // e.g. tcp://localhost:1883
+	ClientID string        `yaml:"client_id,omitempty"`
+	Username string        `yaml:"username,omitempty"`
+	Password string        `yaml:"password,omitempty"`
+	Topic    string        `yaml:"topic,omitempty"`  // topic prefix
+	Format   string        `yaml:"format,omitempty"` // json or topics
+	QoS      byte          `yaml:"qos,omitempty"`
+	Retain   bool          `yaml:"retain,omitempty"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+
+	HomeAssistant   bool   `yaml:"home_assistant,omitempty"`   // publish Home Assistant discovery payloads, needs the topics format
+	DiscoveryPrefix string `yaml:"discovery_prefix,omitempty"` // Home Assistant discovery topic prefix
+}
+
+// Validate checks the mqtt block of the config file.
+func (c *MqttConfig) Validate() error {
+	var errs []error
+	if u, err := url.Parse(c.Broker); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, errors.New("broker must be a URL like tcp://localhost:1883"))
+	}
+
+	if c.Format != "" && c.Format != MqttFormatJson && c.Format != MqttFormatTopics {
+		errs = append(errs, fmt.Errorf("unsupported format %q, use %q or %q", c.Format, MqttFormatJson, MqttFormatTopics))
+	}
+
+	if c.QoS > 2 {
+		errs = append(errs, errors.New("qos must be 0, 1 or 2"))
+	}
+
+	if c.HomeAssistant && c.Format != MqttFormatTopics {
+		errs = append(errs, fmt.Errorf("home_assistant discovery needs the %q format", MqttFormatTopics))
+	}
+
+	if c.Interval < 0 {
+		errs = append(errs, errors.New("interval must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// MqttMessage is a single message published to the broker.
+type MqttMessage struct {
+	Topic    string
+	Payload  []byte
+	Retained bool
+
+	announces string // Home Assistant sensor of the discovery payload
+}
+
+// mqttSample is a single value of a gathered metric.
+type mqttSample struct {
+	name    string
+	device  string
+	labels  []*dto.LabelPair // without the device label
+	value   float64
+	counter bool
+}
+
+// MqttPublisher periodically publishes the metrics of the gatherer to an MQTT broker.
+type MqttPublisher struct {
+	config   MqttConfig
+	gatherer prometheus.Gatherer
+	client   mqtt.Client
+
+	announced map[string]bool // Home Assistant sensors with published discovery payload
+}
+
+func NewMqttPublisher(config MqttConfig, gatherer prometheus.Gatherer) *MqttPublisher {
+	options := mqtt.NewClientOptions().
+		AddBroker(config.Broker).
+		SetClientID(config.ClientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true)
+
+	return &MqttPublisher{
+		config:    config,
+		gatherer:  gatherer,
+		client:    mqtt.NewClient(options),
+		announced: make(map[string]bool),
+	}
+}
+
+// Connect connects to the broker. Failed connections are retried in the background.
+func (p *MqttPublisher) Connect() error {
+	token := p.client.Connect()
+	if !token.WaitTimeout(10*time.Second) && !p.client.IsConnected() {
+		slog.Warn("MQTT broker is not reachable yet, retrying in the background", "broker", p.config.Broker)
+		return nil
+	}
+
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+
+	return nil
+}
+
+// Run publishes the metrics every interval until the context is cancelled.
+func (p *MqttPublisher) Run(ctx context.Context) {
+	defer p.client.Disconnect(250)
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Publish(); err != nil {
+			slog.Error("failed to publish metrics to MQTT", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Publish collects the metrics once and publishes them.
+func (p *MqttPublisher) Publish() error {
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	if err != nil {
+		slog.Warn("metrics gathered with errors", "error", err)
+	}
+
+	var errs []error
+	for _, message := range p.Messages(families, time.Now()) {
+		token := p.client.Publish(message.Topic, p.config.QoS, message.Retained, message.Payload)
+		if !token.WaitTimeout(10 * time.Second) {
+			errs = append(errs, fmt.Errorf("publishing to %s timed out", message.Topic))
+			continue
+		}
+
+		if err := token.Error(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to publish to %s: %w", message.Topic, err))
+			continue
+		}
+
+		if message.announces != "" {
+			p.announced[message.announces] = true
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Messages converts the metric families to messages in the configured format. Home Assistant
+// discovery payloads are included for sensors which were not announced yet, a sensor is
+// announced once its discovery payload is published.
+func (p *MqttPublisher) Messages(families []*dto.MetricFamily, timestamp time.Time) []MqttMessage {
+	samples := mqttSamples(families)
+
+	if p.config.Format == MqttFormatTopics {
+		return p.topicMessages(samples)
+	}
+
+	return p.jsonMessages(samples, timestamp)
+}
+
+func (p *MqttPublisher) jsonMessages(samples []mqttSample, timestamp time.Time) []MqttMessage {
+	type metric struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels,omitempty"`
+		Value  float64           `json:"value"`
+	}
+
+	type document struct {
+		Device  string   `json:"device"`
+		Time    string   `json:"time"`
+		Metrics []metric `json:"metrics"`
+	}
+
+	var devices []string
+	documents := make(map[string]*document)
+	for _, sample := range samples {
+		doc, ok := documents[sample.device]
+		if !ok {
+			doc = &document{Device: sample.device, Time: timestamp.UTC().Format(time.RFC3339)}
+			documents[sample.device] = doc
+			devices = append(devices, sample.device)
+		}
+
+		m := metric{Name: sample.name, Value: sample.value}
+		if len(sample.labels) > 0 {
+			m.Labels = make(map[string]string, len(sample.labels))
+			for _, label := range sample.labels {
+				m.Labels[label.GetName()] = label.GetValue()
+			}
+		}
+		doc.Metrics = append(doc.Metrics, m)
+	}
+
+	messages := make([]MqttMessage, 0, len(devices))
+	for _, device := range devices {
+		payload, err := json.Marshal(documents[device])
+		if err != nil {
+			slog.Error("failed to encode MQTT payload", "device", device, "error", err)
+			continue
+		}
+
+		messages = append(messages, MqttMessage{
+			Topic:    p.config.Topic + "/" + mqttTopicSegment(device),
+			Payload:  payload,
+			Retained: p.config.Retain,
+		})
+	}
+
+	return messages
+}
+
+func (p *MqttPublisher) topicMessages(samples []mqttSample) []MqttMessage {
+	// device model and firmware for the Home Assistant device registry
+	info := make(map[string]map[string]string)
+	for _, sample := range samples {
+		if sample.name == "teltonika_device_info" {
+			info[sample.device] = make(map[string]string)
+			for _, label := range sample.labels {
+				info[sample.device][label.GetName()] = label.GetValue()
+			}
+		}
+	}
+
+	var messages []MqttMessage
+	for _, sample := range samples {
+		topic, name := p.sampleTopic(sample)
+		messages = append(messages, MqttMessage{
+			Topic:    topic,
+			Payload:  []byte(strconv.FormatFloat(sample.value, 'f', -1, 64)),
+			Retained: p.config.Retain,
+		})
+
+		if !p.config.HomeAssistant || strings.HasSuffix(sample.name, "_info") {
+			continue
+		}
+
+		id := mqttObjectId(topic)
+		if p.announced[id] {
+			continue
+		}
+
+		discovery := map[string]any{
+			"name":        name,
+			"unique_id":   id,
+			"state_topic": topic,
+			"state_class": "measurement",
+			"device": map[string]any{
+				"identifiers":  []string{"teltonika_" + mqttTopicSegment(sample.device)},
+				"name":         sample.device,
+				"manufacturer": "Teltonika",
+				"model":        info[sample.device]["model"],
+				"sw_version":   info[sample.device]["firmware"],
+			},
+		}
+		if sample.counter {
+			discovery["state_class"] = "total_increasing"
+		}
+
+		payload, err := json.Marshal(discovery)
+		if err != nil {
+			slog.Error("failed to encode Home Assistant discovery payload", "sensor", id, "error", err)
+			continue
+		}
+
+		messages = append(messages, MqttMessage{
+			Topic:     p.config.DiscoveryPrefix + "/sensor/" + id + "/config",
+			Payload:   payload,
+			Retained:  true, // Home Assistant needs the discovery payloads after its restart
+			announces: id,
+		})
+	}
+
+	return messages
+}
+
+// sampleTopic returns the topic of the sample and a human-readable sensor name. The metric name
+// is split into a group and the rest, label values are placed between them:
+// teltonika_mobile_rsrp{device="RUTX50",sim="1"} is published to teltonika/RUTX50/mobile/1/rsrp.
+func (p *MqttPublisher) sampleTopic(sample mqttSample) (string, string) {
+	group, rest, _ := strings.Cut(strings.TrimPrefix(sample.name, "teltonika_"), "_")
+
+	segments := []string{p.config.Topic, mqttTopicSegment(sample.device), group}
+	names := []string{group}
+	for _, label := range sample.labels {
+		segments = append(segments, mqttTopicSegment(label.GetValue()))
+		names = append(names, label.GetValue())
+	}
+	if rest != "" {
+		segments = append(segments, rest)
+		names = append(names, strings.ReplaceAll(rest, "_", " "))
+	}
+
+	return strings.Join(segments, "/"), strings.Join(names, " ")
+}
+
+// mqttSamples flattens the metric families. Histograms are published as their count and sum.
+func mqttSamples(families []*dto.MetricFamily) []mqttSample {
+	var samples []mqttSample
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			sample := mqttSample{name: family.GetName(), device: "exporter"}
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "device" {
+					sample.device = label.GetValue()
+					continue
+				}
+				sample.labels = append(sample.labels, label)
+			}
+
+			var values []mqttSample
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				sample.value = metric.GetGauge().GetValue()
+				values = append(values, sample)
+			case dto.MetricType_COUNTER:
+				sample.value = metric.GetCounter().GetValue()
+				sample.counter = true
+				values = append(values, sample)
+			case dto.MetricType_HISTOGRAM:
+				// not counters for Home Assistant, a histogram of the current state goes down as well
+				count, sum := sample, sample
+				count.name, count.value = sample.name+"_count", float64(metric.GetHistogram().GetSampleCount())
+				sum.name, sum.value = sample.name+"_sum", metric.GetHistogram().GetSampleSum()
+				values = append(values, count, sum)
+			default:
+				sample.value = metric.GetUntyped().GetValue()
+				values = append(values, sample)
+			}
+
+			for _, value := range values {
+				if !math.IsNaN(value.value) && !math.IsInf(value.value, 0) {
+					samples = append(samples, value)
+				}
+			}
+		}
+	}
+
+	return samples
+}
+
+// mqttObjectId returns the Home Assistant object ID of the topic. Home Assistant accepts only
+// letters, digits, underscores and hyphens, e.g. the dots of a device named by its IP are replaced.
+func mqttObjectId(topic string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, topic)
+}
+
+// mqttTopicSegment replaces characters with a special meaning in MQTT topics.
+func mqttTopicSegment(s string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_", " ", "_").Replace(s)
+}
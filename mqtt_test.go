@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mqttserver "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// embeddedBroker starts an in-process MQTT broker and returns its address and the received messages.
+func embeddedBroker(t *testing.T) (string, func() map[string]packets.Packet) {
+	t.Helper()
+
+	server := mqttserver.New(&mqttserver.Options{InlineClient: true})
+	require.NoError(t, server.AddHook(new(auth.AllowHook), nil))
+
+	tcp := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	require.NoError(t, server.AddListener(tcp))
+	go func() { _ = server.Serve() }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	mtx := sync.Mutex{}
+	received := make(map[string]packets.Packet)
+	require.NoError(t, server.Subscribe("#", 1, func(cl *mqttserver.Client, sub packets.Subscription, pk packets.Packet) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		received[pk.TopicName] = pk
+	}))
+
+	return "tcp://" + tcp.Address(), func() map[string]packets.Packet {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return received
+	}
+}
+
+func TestMqttPublisher_PublishTopics(t *testing.T) {
+	broker, received := embeddedBroker(t)
+
+	publisher := NewMqttPublisher(MqttConfig{
+		Broker:          broker,
+		ClientID:        "test",
+		Topic:           "teltonika",
+		Format:          MqttFormatTopics,
+		QoS:             1,
+		Retain:          true,
+		HomeAssistant:   true,
+		DiscoveryPrefix: "homeassistant",
+	}, influxTestRegistry(t))
+	require.NoError(t, publisher.Connect())
+	defer publisher.client.Disconnect(0)
+
+	require.NoError(t, publisher.Publish())
+	require.Eventually(t, func() bool {
+		_, ok := received()["teltonika/RUT007/mobile/2-1/rsrp"]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	messages := received()
+	assert.Equal(t, "-83", string(messages["teltonika/RUT007/mobile/2-1/rsrp"].Payload))
+	assert.True(t, messages["teltonika/RUT007/mobile/2-1/rsrp"].FixedHeader.Retain)
+	assert.Equal(t, "9000", string(messages["teltonika/RUT007/hotspot/hotspot1/session_max_duration_seconds"].Payload))
+
+	require.Contains(t, messages, "homeassistant/sensor/teltonika_RUT007_mobile_2-1_rsrp/config")
+	var discovery map[string]any
+	require.NoError(t, json.Unmarshal(messages["homeassistant/sensor/teltonika_RUT007_mobile_2-1_rsrp/config"].Payload, &discovery))
+	assert.Equal(t, "mobile 2-1 rsrp", discovery["name"])
+	assert.Equal(t, "teltonika/RUT007/mobile/2-1/rsrp", discovery["state_topic"])
+	assert.Equal(t, "measurement", discovery["state_class"])
+	assert.Equal(t, "RUTX50", discovery["device"].(map[string]any)["model"])
+	assert.NotContains(t, messages, "homeassistant/sensor/teltonika_RUT007_device_info/config")
+}
+
+func TestMqttPublisher_MessagesJson(t *testing.T) {
+	families, err := influxTestRegistry(t).Gather()
+	require.NoError(t, err)
+
+	publisher := NewMqttPublisher(MqttConfig{Topic: "teltonika", Format: MqttFormatJson}, influxTestRegistry(t))
+	messages := publisher.Messages(families, time.Unix(1747248500, 0))
+	require.Len(t, messages, 1)
+	assert.Equal(t, "teltonika/RUT007", messages[0].Topic)
+
+	var document struct {
+		Device  string `json:"device"`
+		Time    string `json:"time"`
+		Metrics []struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+			Value  float64           `json:"value"`
+		} `json:"metrics"`
+	}
+	require.NoError(t, json.Unmarshal(messages[0].Payload, &document))
+	assert.Equal(t, "RUT007", document.Device)
+	assert.Equal(t, "2025-05-14T18:48:20Z", document.Time)
+	assert.Contains(t, document.Metrics, struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels"`
+		Value  float64           `json:"value"`
+	}{Name: "teltonika_mobile_rsrp", Labels: map[string]string{"sim": "2-1"}, Value: -83})
+}
+
+func TestMqttPublisher_AnnouncesOnce(t *testing.T) {
+	broker, _ := embeddedBroker(t)
+	registry := influxTestRegistry(t)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	publisher := NewMqttPublisher(MqttConfig{
+		Broker:          broker,
+		ClientID:        "test",
+		Topic:           "teltonika",
+		Format:          MqttFormatTopics,
+		HomeAssistant:   true,
+		DiscoveryPrefix: "homeassistant",
+	}, registry)
+	first := publisher.Messages(families, time.Now())
+
+	// failed discovery payloads are published again
+	require.Error(t, publisher.Publish(), "not connected yet")
+	assert.Len(t, publisher.Messages(families, time.Now()), len(first))
+
+	require.NoError(t, publisher.Connect())
+	defer publisher.client.Disconnect(0)
+
+	require.NoError(t, publisher.Publish())
+	assert.Less(t, len(publisher.Messages(families, time.Now())), len(first))
+}
+
+func TestMqttPublisher_MessagesDiscovery(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name: proto.String("teltonika_wireless_scan_signal"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{
+					{Name: proto.String("device"), Value: proto.String("192.168.1.1")},
+					{Name: proto.String("encryption"), Value: proto.String("WPA2 PSK (CCMP)")},
+				},
+				Gauge: &dto.Gauge{Value: proto.Float64(-70)},
+			}},
+		},
+		{
+			Name: proto.String("teltonika_request_duration_seconds"),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{
+				Label:     []*dto.LabelPair{{Name: proto.String("device"), Value: proto.String("192.168.1.1")}},
+				Histogram: &dto.Histogram{SampleCount: proto.Uint64(2), SampleSum: proto.Float64(90)},
+			}},
+		},
+	}
+
+	publisher := NewMqttPublisher(MqttConfig{Topic: "teltonika", Format: MqttFormatTopics, HomeAssistant: true, DiscoveryPrefix: "homeassistant"}, nil)
+	discovery := make(map[string]map[string]any)
+	for _, message := range publisher.Messages(families, time.Now()) {
+		if strings.HasPrefix(message.Topic, "homeassistant/") {
+			var payload map[string]any
+			require.NoError(t, json.Unmarshal(message.Payload, &payload))
+			discovery[message.Topic] = payload
+		}
+	}
+
+	id := "teltonika_192_168_1_1_wireless_WPA2_PSK__CCMP__scan_signal"
+	require.Contains(t, discovery, "homeassistant/sensor/"+id+"/config")
+	assert.Equal(t, id, discovery["homeassistant/sensor/"+id+"/config"]["unique_id"])
+	assert.Equal(t, "teltonika/192.168.1.1/wireless/WPA2_PSK_(CCMP)/scan_signal", discovery["homeassistant/sensor/"+id+"/config"]["state_topic"])
+
+	count := discovery["homeassistant/sensor/teltonika_192_168_1_1_request_duration_seconds_count/config"]
+	require.NotNil(t, count)
+	assert.Equal(t, "measurement", count["state_class"], "histogram parts may go down")
+}
+
+func TestMqttObjectId(t *testing.T) {
+	assert.Equal(t, "teltonika_192_168_1_1_wireless_WPA2_PSK__CCMP_", mqttObjectId("teltonika/192.168.1.1/wireless/WPA2 PSK (CCMP)"))
+	assert.Equal(t, "a-b_c", mqttObjectId("a-b_c"))
+}
+
+func TestMqttTopicSegment(t *testing.T) {
+	assert.Equal(t, "a_b_c_d_e", mqttTopicSegment("a/b+c#d e"))
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// OTLP transport protocols.
+const (
+	OtlpProtocolGrpc = "grpc"
+	OtlpProtocolHttp = "http"
+)
+
+// OtlpConfig enables exporting of the metrics to an OpenTelemetry collector.
+type OtlpConfig struct {
+	Protocol string            `yaml:"protocol,omitempty"` // grpc or http
+	Endpoint string            `yaml:"endpoint"`           // host:port of the collector
+	Insecure bool              `yaml:"insecure,omitempty"` // disable TLS
+	Headers  map[string]string `yaml:"headers,omitempty"`  // e.g. authentication headers
+	Interval time.Duration     `yaml:"interval,omitempty"` // export interval
+
+	DisableMetricsEndpoint bool `yaml:"disable_metrics_endpoint,omitempty"` // export only over OTLP, do not serve /metrics
+}
+
+// Validate checks the otlp block of the config file.
+func (c *OtlpConfig) Validate() error {
+	var errs []error
+	if c.Endpoint == "" {
+		errs = append(errs, errors.New("missing endpoint"))
+	}
+
+	if c.Protocol != "" && c.Protocol != OtlpProtocolGrpc && c.Protocol != OtlpProtocolHttp {
+		errs = append(errs, fmt.Errorf("unsupported protocol %q, use %q or %q", c.Protocol, OtlpProtocolGrpc, OtlpProtocolHttp))
+	}
+
+	if c.Interval < 0 {
+		errs = append(errs, errors.New("interval must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// otlpCounters are the byte counters exported as prometheus gauges, OTLP exports them as
+// monotonic sums like the other counters.
+var otlpCounters = []string{
+	"teltonika_mobile_data_received",
+	"teltonika_mobile_data_sent",
+}
+
+// otlpSeries tracks a cumulative series to move its start time on counter resets.
+type otlpSeries struct {
+	start time.Time // start of the cumulative value
+	seen  time.Time // last export of the series
+	value float64
+}
+
+// OtlpExporter periodically exports the metrics of the gatherer over OTLP. Every device is
+// exported as a separate resource carrying its name, model, serial and configured attributes.
+type OtlpExporter struct {
+	config     OtlpConfig
+	gatherer   prometheus.Gatherer
+	exporter   sdkmetric.Exporter
+	attributes map[string]map[string]string // custom resource attributes by device name
+	start      time.Time                    // start of the cumulative counters
+	series     map[string]*otlpSeries       // cumulative series by metric name and labels
+}
+
+func NewOtlpExporter(ctx context.Context, config OtlpConfig, gatherer prometheus.Gatherer, attributes map[string]map[string]string) (*OtlpExporter, error) {
+	var exporter sdkmetric.Exporter
+	var err error
+
+	switch config.Protocol {
+	case OtlpProtocolHttp:
+		options := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(config.Endpoint), otlpmetrichttp.WithHeaders(config.Headers)}
+		if config.Insecure {
+			options = append(options, otlpmetrichttp.WithInsecure())
+		}
+		exporter, err = otlpmetrichttp.New(ctx, options...)
+	default:
+		options := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(config.Endpoint), otlpmetricgrpc.WithHeaders(config.Headers)}
+		if config.Insecure {
+			options = append(options, otlpmetricgrpc.WithInsecure())
+		}
+		exporter, err = otlpmetricgrpc.New(ctx, options...)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	return &OtlpExporter{
+		config:     config,
+		gatherer:   gatherer,
+		exporter:   exporter,
+		attributes: attributes,
+		start:      time.Now(),
+	}, nil
+}
+
+// Run exports the metrics every interval until the context is cancelled.
+func (e *OtlpExporter) Run(ctx context.Context) {
+	defer func() {
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := e.exporter.Shutdown(shutdown); err != nil {
+			slog.Error("failed to shut down OTLP exporter", "error", err)
+		}
+	}()
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Export(ctx); err != nil {
+			slog.Error("failed to export metrics over OTLP", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Export collects the metrics once and exports them.
+func (e *OtlpExporter) Export(ctx context.Context) error {
+	families, err := e.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	if err != nil {
+		slog.Warn("metrics gathered with errors", "error", err)
+	}
+
+	var errs []error
+	for _, metrics := range e.ResourceMetrics(families, time.Now()) {
+		if err := e.exporter.Export(ctx, metrics); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ResourceMetrics converts the metric families to OTLP data, one resource per device. Counters
+// become monotonic cumulative sums, gauges stay gauges and histograms keep their buckets.
+// The start time of a cumulative series moves forward when its value drops, e.g. after a
+// router reboot.
+func (e *OtlpExporter) ResourceMetrics(families []*dto.MetricFamily, now time.Time) []*metricdata.ResourceMetrics {
+	if e.series == nil {
+		e.series = make(map[string]*otlpSeries)
+	}
+
+	var devices []string
+	metrics := make(map[string][]metricdata.Metrics)
+	info := make(map[string][]*dto.LabelPair)
+
+	for _, family := range families {
+		byDevice := make(map[string][]*dto.Metric)
+		var familyDevices []string
+		for _, metric := range family.GetMetric() {
+			device := "exporter"
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "device" {
+					device = label.GetValue()
+				}
+			}
+
+			if !slices.Contains(devices, device) {
+				devices = append(devices, device)
+			}
+			if _, ok := byDevice[device]; !ok {
+				familyDevices = append(familyDevices, device)
+			}
+			byDevice[device] = append(byDevice[device], metric)
+
+			if family.GetName() == "teltonika_device_info" {
+				info[device] = metric.GetLabel()
+			}
+		}
+
+		for _, device := range familyDevices {
+			if data := e.aggregation(family.GetName(), family.GetType(), byDevice[device], now); data != nil {
+				metrics[device] = append(metrics[device], metricdata.Metrics{
+					Name:        family.GetName(),
+					Description: family.GetHelp(),
+					Data:        data,
+				})
+			}
+		}
+	}
+
+	// forget the series which are gone, they start over when they come back
+	for key, series := range e.series {
+		if !series.seen.Equal(now) {
+			delete(e.series, key)
+		}
+	}
+
+	resourceMetrics := make([]*metricdata.ResourceMetrics, 0, len(devices))
+	for _, device := range devices {
+		resourceMetrics = append(resourceMetrics, &metricdata.ResourceMetrics{
+			Resource: e.resource(device, info[device]),
+			ScopeMetrics: []metricdata.ScopeMetrics{{
+				Scope:   instrumentation.Scope{Name: "teltonika-exporter"},
+				Metrics: metrics[device],
+			}},
+		})
+	}
+
+	return resourceMetrics
+}
+
+func (e *OtlpExporter) resource(device string, info []*dto.LabelPair) *resource.Resource {
+	attributes := []attribute.KeyValue{
+		attribute.String("service.name", "teltonika-exporter"),
+		attribute.String("device.manufacturer", "Teltonika"),
+		attribute.String("teltonika.device.name", device),
+	}
+
+	for _, label := range info {
+		switch label.GetName() {
+		case "model":
+			attributes = append(attributes, attribute.String("device.model.name", label.GetValue()))
+		case "serial":
+			attributes = append(attributes, attribute.String("device.id", label.GetValue()))
+		case "firmware":
+			attributes = append(attributes, attribute.String("teltonika.device.firmware", label.GetValue()))
+		}
+	}
+
+	for key, value := range e.attributes[device] {
+		attributes = append(attributes, attribute.String(key, value))
+	}
+
+	return resource.NewSchemaless(attributes...)
+}
+
+func (e *OtlpExporter) aggregation(name string, kind dto.MetricType, metrics []*dto.Metric, now time.Time) metricdata.Aggregation {
+	if kind == dto.MetricType_GAUGE && slices.Contains(otlpCounters, name) {
+		kind = dto.MetricType_COUNTER
+	}
+
+	switch kind {
+	case dto.MetricType_COUNTER:
+		sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+		for _, metric := range metrics {
+			value := metric.GetCounter().GetValue()
+			if metric.GetGauge() != nil {
+				value = metric.GetGauge().GetValue()
+			}
+
+			sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: otlpAttributes(metric),
+				StartTime:  e.startTime(name, metric, value, now),
+				Time:       now,
+				Value:      value,
+			})
+		}
+		return sum
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		gauge := metricdata.Gauge[float64]{}
+		for _, metric := range metrics {
+			value := metric.GetGauge().GetValue()
+			if kind == dto.MetricType_UNTYPED {
+				value = metric.GetUntyped().GetValue()
+			}
+
+			gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: otlpAttributes(metric),
+				Time:       now,
+				Value:      value,
+			})
+		}
+		return gauge
+	case dto.MetricType_HISTOGRAM:
+		histogram := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+		for _, metric := range metrics {
+			h := metric.GetHistogram()
+
+			// prometheus buckets are cumulative, OTLP counts every bucket on its own
+			var bounds []float64
+			var counts []uint64
+			var previous uint64
+			for _, bucket := range h.GetBucket() {
+				bounds = append(bounds, bucket.GetUpperBound())
+				counts = append(counts, bucket.GetCumulativeCount()-previous)
+				previous = bucket.GetCumulativeCount()
+			}
+			counts = append(counts, h.GetSampleCount()-previous) // +Inf bucket
+
+			histogram.DataPoints = append(histogram.DataPoints, metricdata.HistogramDataPoint[float64]{
+				Attributes:   otlpAttributes(metric),
+				StartTime:    e.startTime(name, metric, float64(h.GetSampleCount()), now),
+				Time:         now,
+				Count:        h.GetSampleCount(),
+				Bounds:       bounds,
+				BucketCounts: counts,
+				Sum:          h.GetSampleSum(),
+			})
+		}
+		return histogram
+	default:
+		return nil
+	}
+}
+
+// startTime returns the start of the cumulative series. The series restarts at its previous
+// export when the value dropped, the counters of the router were reset in between.
+func (e *OtlpExporter) startTime(name string, metric *dto.Metric, value float64, now time.Time) time.Time {
+	key := name
+	for _, label := range metric.GetLabel() {
+		key += "\xff" + label.GetName() + "=" + label.GetValue()
+	}
+
+	series, ok := e.series[key]
+	switch {
+	case !ok:
+		series = &otlpSeries{start: e.start}
+		e.series[key] = series
+	case value < series.value:
+		series.start = series.seen
+	}
+	series.value = value
+	series.seen = now
+
+	return series.start
+}
+
+// otlpAttributes returns the metric labels as data point attributes. The device label is
+// a resource attribute.
+func otlpAttributes(metric *dto.Metric) attribute.Set {
+	var attributes []attribute.KeyValue
+	for _, label := range metric.GetLabel() {
+		if label.GetName() != "device" {
+			attributes = append(attributes, attribute.String(label.GetName(), label.GetValue()))
+		}
+	}
+
+	return attribute.NewSet(attributes...)
+}
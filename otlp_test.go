@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOtlpExporter_ResourceMetrics(t *testing.T) {
+	families, err := influxTestRegistry(t).Gather()
+	require.NoError(t, err)
+
+	exporter := &OtlpExporter{
+		attributes: map[string]map[string]string{"RUT007": {"site": "prague"}},
+		start:      time.Unix(1747248000, 0),
+	}
+	resources := exporter.ResourceMetrics(families, time.Unix(1747248500, 0))
+	require.Len(t, resources, 1)
+
+	resource := resources[0].Resource
+	for key, value := range map[string]string{
+		"teltonika.device.name": "RUT007",
+		"device.model.name":     "RUTX50",
+		"device.id":             "1111111111",
+		"site":                  "prague",
+	} {
+		v, ok := resource.Set().Value(attribute.Key(key))
+		assert.True(t, ok, key)
+		assert.Equal(t, value, v.AsString(), key)
+	}
+
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, metric := range resources[0].ScopeMetrics[0].Metrics {
+		metrics[metric.Name] = metric.Data
+	}
+
+	rsrp, ok := metrics["teltonika_mobile_rsrp"].(metricdata.Gauge[float64])
+	require.True(t, ok, "signal is a gauge")
+	assert.InDelta(t, -83, rsrp.DataPoints[0].Value, 0)
+	sim, _ := rsrp.DataPoints[0].Attributes.Value("sim")
+	assert.Equal(t, "2-1", sim.AsString())
+	assert.False(t, rsrp.DataPoints[0].Attributes.HasValue("device"), "device is a resource attribute")
+
+	received, ok := metrics["teltonika_hotspot_client_received_bytes_total"].(metricdata.Sum[float64])
+	require.True(t, ok, "byte counter is a sum")
+	assert.True(t, received.IsMonotonic)
+	assert.Equal(t, metricdata.CumulativeTemporality, received.Temporality)
+
+	sent, ok := metrics["teltonika_mobile_data_sent"].(metricdata.Sum[float64])
+	require.True(t, ok, "mobile byte gauge is a sum")
+	assert.True(t, sent.IsMonotonic)
+	assert.Equal(t, time.Unix(1747248000, 0), sent.DataPoints[0].StartTime)
+
+	duration, ok := metrics["teltonika_hotspot_session_max_duration_seconds"].(metricdata.Gauge[float64])
+	require.True(t, ok, "session duration is a gauge")
+	assert.InDelta(t, 9000, duration.DataPoints[0].Value, 0)
+}
+
+func TestOtlpExporter_Histogram(t *testing.T) {
+	families := []*dto.MetricFamily{{
+		Name: proto.String("teltonika_request_duration_seconds"),
+		Type: dto.MetricType_HISTOGRAM.Enum(),
+		Metric: []*dto.Metric{{
+			Label: []*dto.LabelPair{{Name: proto.String("device"), Value: proto.String("RUT007")}},
+			Histogram: &dto.Histogram{
+				SampleCount: proto.Uint64(3),
+				SampleSum:   proto.Float64(4.5),
+				Bucket: []*dto.Bucket{
+					{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(1)},
+					{UpperBound: proto.Float64(5), CumulativeCount: proto.Uint64(3)},
+				},
+			},
+		}},
+	}}
+
+	exporter := &OtlpExporter{start: time.Unix(1747248000, 0)}
+	resources := exporter.ResourceMetrics(families, time.Unix(1747248500, 0))
+	histogram, ok := resources[0].ScopeMetrics[0].Metrics[0].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), histogram.DataPoints[0].Count)
+	assert.Equal(t, []float64{1, 5}, histogram.DataPoints[0].Bounds)
+	assert.Equal(t, []uint64{1, 2, 0}, histogram.DataPoints[0].BucketCounts)
+}
+
+func TestOtlpExporter_CounterReset(t *testing.T) {
+	family := func(value float64) []*dto.MetricFamily {
+		return []*dto.MetricFamily{{
+			Name: proto.String("teltonika_hotspot_client_received_bytes_total"),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{{
+				Label:   []*dto.LabelPair{{Name: proto.String("device"), Value: proto.String("RUT007")}},
+				Counter: &dto.Counter{Value: proto.Float64(value)},
+			}},
+		}}
+	}
+	startTime := func(resources []*metricdata.ResourceMetrics) time.Time {
+		return resources[0].ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[float64]).DataPoints[0].StartTime
+	}
+
+	start := time.Unix(1747248000, 0)
+	exporter := &OtlpExporter{start: start}
+
+	assert.Equal(t, start, startTime(exporter.ResourceMetrics(family(100), start.Add(time.Minute))))
+	assert.Equal(t, start, startTime(exporter.ResourceMetrics(family(200), start.Add(2*time.Minute))))
+
+	// the router rebooted between the exports
+	assert.Equal(t, start.Add(2*time.Minute), startTime(exporter.ResourceMetrics(family(10), start.Add(3*time.Minute))))
+	assert.Equal(t, start.Add(2*time.Minute), startTime(exporter.ResourceMetrics(family(20), start.Add(4*time.Minute))))
+}
+
+func TestOtlpExporter_ExportHttp(t *testing.T) {
+	mtx := sync.Mutex{}
+	var request collectormetrics.ExportMetricsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.NoError(t, proto.Unmarshal(body, &request))
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter, err := NewOtlpExporter(t.Context(), OtlpConfig{
+		Protocol: OtlpProtocolHttp,
+		Endpoint: strings.TrimPrefix(server.URL, "http://"),
+		Insecure: true,
+	}, influxTestRegistry(t), nil)
+	require.NoError(t, err)
+	require.NoError(t, exporter.Export(t.Context()))
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Len(t, request.GetResourceMetrics(), 1)
+
+	var names []string
+	for _, metric := range request.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics() {
+		names = append(names, metric.GetName())
+	}
+	assert.Contains(t, names, "teltonika_mobile_signal_strength")
+}
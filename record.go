@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+var recordOutput string // Fixture output directory
+
+var recordCmd = cobra.Command{
+	Use:     "record",
+	Example: "./teltonika-exporter record --config path/to/config.yaml --output fixtures",
+	Short:   "Record API responses of the configured devices as test fixtures",
+	Long: `Logs in to every configured device, fetches the endpoints of all known sections and writes the responses
+to <output>/<device name>. IMEIs, ICCIDs, IMSIs, MAC addresses, serial numbers, tokens, phone numbers, host
+names, public keys and public IP addresses are replaced by stable pseudonyms, so the fixtures can be attached to
+bug reports. Endpoints the device does not provide are skipped.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configFile == "" {
+			return fmt.Errorf("no config file specified")
+		}
+
+		config, err := ParseConfig(configFile)
+		if err != nil {
+			return fmt.Errorf("error parsing config file: %w", err)
+		}
+
+		collector, err := NewCollector(cmd.Context(), config, NewMetrics())
+		if err != nil {
+			return fmt.Errorf("error creating collector: %w", err)
+		}
+
+		var endpoints []string
+		for _, section := range collector.sections.Sections() {
+			endpoints = append(endpoints, section.Endpoints()...)
+		}
+
+		failed := 0
+		for _, device := range collector.devices {
+			dir := filepath.Join(recordOutput, device.name)
+			results, err := device.Record(dir, NewRedactor(), endpoints)
+			if err != nil {
+				failed++
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", device.name, err)
+				continue
+			}
+
+			for _, result := range results {
+				switch {
+				case errors.Is(result.Err, ErrEndpointNotFound):
+					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: skipped %s, not available\n", device.name, result.Endpoint)
+				case result.Err != nil:
+					failed++
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", device.name, result.Err)
+				default:
+					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: recorded %s\n", device.name, filepath.Join(dir, result.File))
+				}
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("recording failed, %d error(s)", failed)
+		}
+
+		return nil
+	},
+}
+
+// FixtureFile returns the name of the fixture file holding the response of the endpoint,
+// e.g. system_device_usage_status.json for /system/device/usage/status.
+func FixtureFile(endpoint string) string {
+	return strings.ReplaceAll(strings.Trim(endpoint, "/"), "/", "_") + ".json"
+}
+
+// RecordResult is the outcome of recording a single endpoint.
+type RecordResult struct {
+	Endpoint string
+	File     string
+	Err      error
+}
+
+// Record logs in to the device and writes redacted responses of the endpoints to the fixture directory.
+// Besides the given endpoints it records the login and session endpoints used by every scrape.
+func (d *Device) Record(dir string, redactor *Redactor, endpoints []string) ([]RecordResult, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.token = ""
+	if err := d.authenticate(); err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+
+	// the login response is not kept by authenticate, the token is all the exporter reads from it
+	login, err := json.Marshal(map[string]any{"success": true, "data": map[string]any{"token": d.token}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode login response: %w", err)
+	}
+
+	results := []RecordResult{d.writeFixture(dir, "/login", login, redactor)}
+
+	var recorded []string
+	for _, endpoint := range slices.Concat([]string{"/session/status", "/system/device/status"}, endpoints) {
+		if slices.Contains(recorded, endpoint) {
+			continue
+		}
+		recorded = append(recorded, endpoint)
+
+		var raw json.RawMessage
+		if err := d.get(endpoint, d.token, &raw); err != nil {
+			results = append(results, RecordResult{Endpoint: endpoint, Err: err})
+			continue
+		}
+
+		results = append(results, d.writeFixture(dir, endpoint, raw, redactor))
+	}
+
+	return results, nil
+}
+
+func (d *Device) writeFixture(dir, endpoint string, content []byte, redactor *Redactor) RecordResult {
+	result := RecordResult{Endpoint: endpoint, File: FixtureFile(endpoint)}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, redactor.Redact(content), "", "  "); err != nil {
+		result.Err = fmt.Errorf("failed to format %s: %w", endpoint, err)
+		return result
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, result.File), out.Bytes(), 0o600); err != nil {
+		result.Err = fmt.Errorf("failed to write %s: %w", result.File, err)
+	}
+
+	return result
+}
+
+// redactedKeys are JSON keys whose values identify the device, its SIM cards, its owner or its peers.
+var redactedKeys = []string{
+	"imei", "iccid", "imsi", "serial", "mac", "token", "password",
+	"sender", "hostname", "public_key",
+}
+
+var macAddress = regexp.MustCompile(`(?i)\b[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}\b`)
+
+var ipv4Address = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`)
+
+// Redactor replaces identifiers in API responses with pseudonyms. The same value always gets the
+// same pseudonym, so references between endpoints (e.g. a client MAC in DHCP leases and wireless
+// clients) are kept.
+type Redactor struct {
+	pseudonyms map[string]string
+	counts     map[string]int
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{
+		pseudonyms: make(map[string]string),
+		counts:     make(map[string]int),
+	}
+}
+
+// Redact returns the JSON document with identifiers replaced. Key order and formatting of numbers are kept.
+func (r *Redactor) Redact(content []byte) []byte {
+	return rewriteJSON(content, r.redactScalar, nil)
+}
+
+func (r *Redactor) redactScalar(key string, value gjson.Result) string {
+	switch {
+	case value.Type == gjson.String:
+		redacted, _ := json.Marshal(r.redactString(key, value.String()))
+		return string(redacted)
+	case value.Type == gjson.Number && slices.Contains(redactedKeys, strings.ToLower(key)):
+		return r.pseudonym(strings.ToLower(key), value.Raw)
+	default:
+		return value.Raw
+	}
+}
+
+func (r *Redactor) redactString(key, value string) string {
+	if value != "" && slices.Contains(redactedKeys, strings.ToLower(key)) && !macAddress.MatchString(value) {
+		return r.pseudonym(strings.ToLower(key), value)
+	}
+
+	value = macAddress.ReplaceAllStringFunc(value, func(mac string) string {
+		return r.pseudonym("mac", strings.ToUpper(strings.ReplaceAll(mac, "-", ":")))
+	})
+
+	// public addresses locate the owner and the VPN peers, local addresses and netmasks are kept
+	return ipv4Address.ReplaceAllStringFunc(value, func(address string) string {
+		ip := net.ParseIP(address).To4()
+		if ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+			return address
+		}
+		if _, bits := net.IPMask(ip).Size(); bits != 0 {
+			return address
+		}
+
+		return r.pseudonym("ip", address)
+	})
+}
+
+// pseudonym returns the pseudonym of the value. Numeric identifiers and phone numbers keep their length,
+// MAC addresses are replaced by locally administered addresses and IP addresses by documentation ones.
+func (r *Redactor) pseudonym(kind, value string) string {
+	id := kind + "\x00" + value
+	if pseudonym, ok := r.pseudonyms[id]; ok {
+		return pseudonym
+	}
+
+	r.counts[kind]++
+	n := r.counts[kind]
+
+	var pseudonym string
+	switch {
+	case kind == "mac":
+		pseudonym = fmt.Sprintf("02:00:00:00:%02X:%02X", n>>8&0xff, n&0xff)
+	case kind == "ip":
+		pseudonym = fmt.Sprintf("192.0.%d.%d", 2+n>>8&0xff, n&0xff)
+	case strings.HasPrefix(value, "+") && isDigits(value[1:]):
+		pseudonym = fmt.Sprintf("+%0*d", len(value)-1, n)
+	case isDigits(value):
+		pseudonym = fmt.Sprintf("%0*d", len(value), n)
+	default:
+		pseudonym = kind + strconv.Itoa(n)
+	}
+
+	r.pseudonyms[id] = pseudonym
+	return pseudonym
+}
+
+func isDigits(value string) bool {
+	return value != "" && strings.Trim(value, "0123456789") == ""
+}
+
+// rewriteJSON rebuilds a JSON document keeping the key order. Scalars are replaced by the raw JSON
+// returned by scalar, which receives the key of the enclosing object field. Object fields and array
+// items for which keep returns false are dropped, keep receives the field key or an empty key for
+// array items. A nil keep keeps all of them.
+func rewriteJSON(content []byte, scalar func(key string, value gjson.Result) string, keep func(key string, value gjson.Result) bool) []byte {
+	var out bytes.Buffer
+
+	var rewrite func(key string, value gjson.Result)
+	rewrite = func(key string, value gjson.Result) {
+		switch {
+		case value.IsObject():
+			out.WriteByte('{')
+			first := true
+			value.ForEach(func(k, v gjson.Result) bool {
+				if keep != nil && !keep(k.String(), v) {
+					return true
+				}
+				if !first {
+					out.WriteByte(',')
+				}
+				first = false
+
+				out.WriteString(k.Raw)
+				out.WriteByte(':')
+				rewrite(k.String(), v)
+				return true
+			})
+			out.WriteByte('}')
+		case value.IsArray():
+			out.WriteByte('[')
+			first := true
+			for _, item := range value.Array() {
+				if keep != nil && !keep("", item) {
+					continue
+				}
+				if !first {
+					out.WriteByte(',')
+				}
+				first = false
+
+				rewrite(key, item)
+			}
+			out.WriteByte(']')
+		default:
+			out.WriteString(scalar(key, value))
+		}
+	}
+
+	rewrite("", gjson.ParseBytes(content))
+	return out.Bytes()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtureFile(t *testing.T) {
+	assert.Equal(t, "login.json", FixtureFile("/login"))
+	assert.Equal(t, "system_device_usage_status.json", FixtureFile("/system/device/usage/status"))
+	assert.Equal(t, "events_log_status.json", FixtureFile("/events_log/status"))
+}
+
+func TestRedactor_Redact(t *testing.T) {
+	r := NewRedactor()
+
+	redacted := r.Redact([]byte(`{"success":true,"data":[` +
+		`{"imei":"866666666666666","iccid":"8988888888888888888","serial":"WTF66QZ1X711748","rssi":-61.5},` +
+		`{"imei":"866666666666667","mac":"14:25:36:ab:aa:44","message":"client 14-25-36-AB-AA-44 connected"}]}`))
+	assert.JSONEq(t, `{"success":true,"data":[`+
+		`{"imei":"000000000000001","iccid":"0000000000000000001","serial":"serial1","rssi":-61.5},`+
+		`{"imei":"000000000000002","mac":"02:00:00:00:00:01","message":"client 02:00:00:00:00:01 connected"}]}`,
+		string(redacted))
+
+	// personal data and public addresses of the owner and its peers
+	assert.JSONEq(t, `{"sender":"+000000000001","public_key":"public_key1","hostname":"hostname1",`+
+		`"real_address":"192.0.2.1:51234","ipaddr":"192.168.1.121","netmask":"255.255.255.0"}`,
+		string(r.Redact([]byte(`{"sender":"+420777000111","public_key":"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=","hostname":"iPhone",`+
+			`"real_address":"198.51.100.20:51234","ipaddr":"192.168.1.121","netmask":"255.255.255.0"}`))))
+
+	// pseudonyms are stable across documents
+	assert.JSONEq(t, `{"mac":"02:00:00:00:00:01","token":"token1"}`,
+		string(r.Redact([]byte(`{"mac":"14:25:36:AB:AA:44","token":"secret_token"}`))))
+
+	// key order is kept
+	assert.Equal(t, `{"b":1,"a":"x"}`, string(r.Redact([]byte(`{"b":1,"a":"x"}`))))
+}
+
+func TestDevice_Record(t *testing.T) {
+	d := testDevice(t, fixtureTransport(t))
+
+	dir := t.TempDir()
+	sections := mustResolveSections(t, DefaultSections, SectionSystem, SectionModem, SectionServices)
+	var endpoints []string
+	for _, section := range sections {
+		endpoints = append(endpoints, section.Endpoints()...)
+	}
+
+	results, err := d.Record(dir, NewRedactor(), endpoints)
+	require.NoError(t, err)
+
+	for _, result := range results {
+		if result.Endpoint == "/samba/status" {
+			assert.ErrorIs(t, result.Err, ErrEndpointNotFound)
+			continue
+		}
+		assert.NoError(t, result.Err, result.Endpoint)
+		assert.FileExists(t, filepath.Join(dir, result.File))
+	}
+
+	modems, err := os.ReadFile(filepath.Join(dir, "modems_status.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(modems), "6666666666666666")
+	assert.NotContains(t, string(modems), "WTF66QZ1X711748")
+
+	login, err := os.ReadFile(filepath.Join(dir, "login.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(login), "secret_token")
+
+	// the recorded fixtures are usable by the mock round tripper
+	replay := testDevice(t, simulatorTransport(t, dir, testsOptionalEndpoints...), sections...)
+	assert.Positive(t, testutil.CollectAndCount(prometheus.CollectorFunc(replay.Collect), "teltonika_mobile_signal_strength"))
+}
+
+func TestDevice_RecordRedactsFixtures(t *testing.T) {
+	d := testDevice(t, fixtureTransport(t))
+
+	var endpoints []string
+	for _, section := range DefaultSections.Sections() {
+		endpoints = append(endpoints, section.Endpoints()...)
+	}
+
+	dir := t.TempDir()
+	_, err := d.Record(dir, NewRedactor(), endpoints)
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		require.NoError(t, err)
+
+		for _, personal := range []string{
+			"+420777000111", "+420777000222", // SMS senders
+			"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=", "TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0=", // WireGuard keys
+			"198.51.100.20", "198.51.100.21", "203.0.113.1", "203.0.113.2", // public IPs
+		} {
+			assert.NotContains(t, string(content), personal, file.Name())
+		}
+	}
+}
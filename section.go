@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	SectionSystem       = "system"
+	SectionModem        = "modem"
+	SectionWireless     = "wireless"
+	SectionDhcp         = "dhcp"
+	SectionWirelessScan = "wireless_scan"
+	SectionVpn          = "vpn"
+	SectionFailover     = "failover"
+	SectionPorts        = "ports"
+	SectionIo           = "io"
+	SectionCpu          = "cpu"
+	SectionEvents       = "events"
+	SectionSms          = "sms"
+	SectionHotspot      = "hotspot"
+	SectionRms          = "rms"
+	SectionServices     = "services"
+)
+
+// Section is a group of metrics collected from one or more API endpoints.
+// Sections are enabled per device by the collect list in the config file.
+type Section interface {
+	// Name is used in the collect list of the config file.
+	Name() string
+	// Endpoints lists all API endpoints called by the section.
+	Endpoints() []string
+	// Describe sends descriptors of all metrics the section can produce.
+	Describe(ch chan<- *prometheus.Desc)
+	// Collect calls the device API and sends the metrics. The device is already authenticated.
+	Collect(d *Device, ch chan<- prometheus.Metric)
+}
+
+// SectionRegistry holds all sections known to the exporter.
+type SectionRegistry struct {
+	sections map[string]Section
+	order    []string
+}
+
+func NewSectionRegistry() *SectionRegistry {
+	return &SectionRegistry{
+		sections: make(map[string]Section),
+	}
+}
+
+// Register adds the section to the registry. Section names must be unique.
+func (r *SectionRegistry) Register(section Section) error {
+	if _, ok := r.sections[section.Name()]; ok {
+		return fmt.Errorf("section %q is already registered", section.Name())
+	}
+
+	r.sections[section.Name()] = section
+	r.order = append(r.order, section.Name())
+	return nil
+}
+
+// MustRegister adds the section to the registry and panics on conflict.
+func (r *SectionRegistry) MustRegister(section Section) {
+	if err := r.Register(section); err != nil {
+		panic(err)
+	}
+}
+
+func (r *SectionRegistry) Get(name string) (Section, bool) {
+	section, ok := r.sections[name]
+	return section, ok
+}
+
+// Names returns names of all sections in the registration order.
+func (r *SectionRegistry) Names() []string {
+	return slices.Clone(r.order)
+}
+
+// Sections returns all sections in the registration order.
+func (r *SectionRegistry) Sections() []Section {
+	sections := make([]Section, len(r.order))
+	for i, name := range r.order {
+		sections[i] = r.sections[name]
+	}
+
+	return sections
+}
+
+// Resolve returns sections for the names from the collect list.
+func (r *SectionRegistry) Resolve(names []string) ([]Section, error) {
+	sections := make([]Section, 0, len(names))
+	for _, name := range names {
+		section, ok := r.sections[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown section %q", name)
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections, nil
+}
+
+// Clone returns a copy of the registry, e.g. to extend the builtin sections by custom collectors.
+func (r *SectionRegistry) Clone() *SectionRegistry {
+	clone := NewSectionRegistry()
+	for _, section := range r.Sections() {
+		clone.MustRegister(section)
+	}
+
+	return clone
+}
+
+func (r *SectionRegistry) Describe(ch chan<- *prometheus.Desc) {
+	for _, section := range r.Sections() {
+		section.Describe(ch)
+	}
+}
+
+// DefaultSections is the registry of builtin sections. Extra sections can be compiled in
+// by registering them from an init function.
+var DefaultSections = NewSectionRegistry()
+
+// RegisterSection adds the section to the default registry.
+func RegisterSection(section Section) {
+	DefaultSections.MustRegister(section)
+}
+
+// builtinMetrics are descriptors of metrics produced by builtin sections.
+var builtinMetrics = NewMetrics()
+
+// builtinSection is a section implemented by the exporter. Its metrics are defined in NewMetrics,
+// the collect functions are called concurrently.
+type builtinSection struct {
+	name      string
+	endpoints []string
+	metrics   []string
+	collect   []func(d *Device, ch chan<- prometheus.Metric)
+}
+
+func (s *builtinSection) Name() string {
+	return s.name
+}
+
+func (s *builtinSection) Endpoints() []string {
+	return s.endpoints
+}
+
+func (s *builtinSection) Describe(ch chan<- *prometheus.Desc) {
+	for _, name := range s.metrics {
+		ch <- builtinMetrics[name]
+	}
+}
+
+func (s *builtinSection) Collect(d *Device, ch chan<- prometheus.Metric) {
+	wg := sync.WaitGroup{}
+	wg.Add(len(s.collect))
+	for _, collect := range s.collect {
+		go func() {
+			defer wg.Done()
+			collect(d, ch)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func init() {
+	RegisterSection(&builtinSection{
+		name:      SectionSystem,
+		endpoints: []string{"/system/device/status", "/system/device/usage/status", "/date_time/ntp/client/status"},
+		metrics: []string{
+			"teltonika_device_info", "teltonika_device_uptime", "teltonika_device_boot_time_seconds", "teltonika_device_clock_offset_seconds",
+			"teltonika_ntp_enabled", "teltonika_ntp_synchronized", "teltonika_ntp_last_sync_timestamp_seconds",
+			"teltonika_cpu_usage", "teltonika_load_min_1", "teltonika_load_min_5", "teltonika_load_min_15",
+			"teltonika_ram_total", "teltonika_ram_used", "teltonika_ram_free", "teltonika_ram_buffered",
+			"teltonika_ram_shared", "teltonika_ram_available", "teltonika_ram_used_percent",
+			"teltonika_flash_total", "teltonika_flash_used", "teltonika_flash_free", "teltonika_flash_used_percent",
+		},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectDeviceInfo,
+			(*Device).collectSystemDeviceUsageStatus,
+			(*Device).collectNtpStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionModem,
+		endpoints: []string{"/modems/status"},
+		metrics: []string{
+			"teltonika_mobile_connected", "teltonika_mobile_signal_strength", "teltonika_mobile_sinr",
+			"teltonika_mobile_rsrp", "teltonika_mobile_rsrq", "teltonika_mobile_data_sent",
+			"teltonika_mobile_data_received", "teltonika_mobile_temperature",
+		},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectModemStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionWireless,
+		endpoints: []string{"/wireless/interfaces/status"},
+		metrics: []string{
+			"teltonika_wireless_device_quality", "teltonika_wireless_device_bitrate",
+			"teltonika_wireless_device_op_class", "teltonika_wireless_device_airtime_time_busy",
+			"teltonika_wireless_device_airtime_time", "teltonika_wireless_device_airtime_utilization",
+			"teltonika_wireless_device_noise", "teltonika_wireless_device_signal",
+			"teltonika_wireless_client_tx_rate", "teltonika_wireless_client_rx_rate",
+			"teltonika_wireless_client_signal", "teltonika_wireless_client_noise",
+		},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectWirelessInterfacesStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionDhcp,
+		endpoints: []string{"/dhcp/leases/ipv4/status", "/dhcp/leases/ipv6/status"},
+		metrics:   []string{"teltonika_dhcp_leases_ipv4", "teltonika_dhcp_leases_ipv6"},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectDhcpLeasesIPv4Status,
+			(*Device).collectDhcpLeasesIPv6Status,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionWirelessScan,
+		endpoints: []string{"/wireless/scan/status"},
+		metrics:   []string{"teltonika_wireless_scan_networks", "teltonika_wireless_scan_signal"},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectWirelessScanStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionVpn,
+		endpoints: []string{"/openvpn/status", "/wireguard/status", "/ipsec/status"},
+		metrics: []string{
+			"teltonika_vpn_up", "teltonika_vpn_peers_connected", "teltonika_vpn_peer_last_handshake_age_seconds",
+			"teltonika_vpn_peer_received_bytes_total", "teltonika_vpn_peer_sent_bytes_total",
+		},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectOpenVpnStatus,
+			(*Device).collectWireGuardStatus,
+			(*Device).collectIpsecStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionFailover,
+		endpoints: []string{"/failover/status"},
+		metrics: []string{
+			"teltonika_failover_interface_active", "teltonika_failover_interface_enabled",
+			"teltonika_failover_interface_online", "teltonika_failover_track_up", "teltonika_failover_track_hosts_up",
+			"teltonika_failover_ping_latency_seconds", "teltonika_failover_ping_loss_percent",
+			"teltonika_failover_events_total",
+		},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectFailoverStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionPorts,
+		endpoints: []string{"/ports/status"},
+		metrics: []string{
+			"teltonika_port_up", "teltonika_port_speed_bits_per_second", "teltonika_port_full_duplex",
+			"teltonika_port_poe_enabled", "teltonika_port_poe_power_watts", "teltonika_port_link_flaps_total",
+		},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectPortsStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionIo,
+		endpoints: []string{"/io/status"},
+		metrics:   []string{"teltonika_io_state", "teltonika_io_analog_value"},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectIoStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionCpu,
+		endpoints: []string{"/system/cpu/status", "/system/processes/status"},
+		metrics: []string{
+			"teltonika_cpu_core_usage_percent", "teltonika_processes",
+			"teltonika_process_cpu_percent", "teltonika_process_memory_bytes",
+		},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectCpuStatus,
+			(*Device).collectProcessesStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionEvents,
+		endpoints: []string{"/events_log/status"},
+		metrics:   []string{"teltonika_events_total"},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectEventsStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionSms,
+		endpoints: []string{"/messages/status", "/messages/storage/status"},
+		metrics:   []string{"teltonika_sms_messages", "teltonika_sms_storage_used", "teltonika_sms_storage_total"},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectMessagesStatus,
+			(*Device).collectMessagesStorageStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionHotspot,
+		endpoints: []string{"/hotspot/sessions/status"},
+		metrics: []string{
+			"teltonika_hotspot_sessions", "teltonika_hotspot_authenticated_users",
+			"teltonika_hotspot_session_max_duration_seconds", "teltonika_hotspot_session_average_duration_seconds",
+			"teltonika_hotspot_client_received_bytes_total", "teltonika_hotspot_client_sent_bytes_total",
+		},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectHotspotSessionsStatus,
+		},
+	})
+
+	RegisterSection(&builtinSection{
+		name:      SectionRms,
+		endpoints: []string{"/rms/status"},
+		metrics: []string{
+			"teltonika_rms_info", "teltonika_rms_enabled", "teltonika_rms_connected",
+			"teltonika_rms_last_connection_timestamp_seconds",
+		},
+		collect: []func(d *Device, ch chan<- prometheus.Metric){
+			(*Device).collectRmsStatus,
+		},
+	})
+
+	servicesSection := &builtinSection{
+		name: SectionServices,
+		metrics: []string{
+			"teltonika_service_enabled", "teltonika_service_running", "teltonika_service_last_update_timestamp_seconds",
+		},
+	}
+	for _, service := range services {
+		servicesSection.endpoints = append(servicesSection.endpoints, service.Endpoint)
+		servicesSection.collect = append(servicesSection.collect, func(d *Device, ch chan<- prometheus.Metric) {
+			d.collectServiceStatus(ch, service)
+		})
+	}
+	RegisterSection(servicesSection)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultSections_Metrics(t *testing.T) {
+	// every metric belongs to exactly one section
+	owners := make(map[string]string)
+
+	for _, section := range DefaultSections.Sections() {
+		builtin, ok := section.(*builtinSection)
+		require.True(t, ok)
+		assert.NotEmpty(t, builtin.Endpoints(), "section %s has no endpoints", section.Name())
+
+		for _, name := range builtin.metrics {
+			assert.NotNil(t, builtinMetrics[name], "section %s uses unknown metric %s", section.Name(), name)
+			assert.Empty(t, owners[name], "metric %s is used by %s and %s", name, owners[name], section.Name())
+			owners[name] = section.Name()
+		}
+	}
+
+	for name := range NewMetrics() {
+		assert.NotEmpty(t, owners[name], "metric %s does not belong to any section", name)
+	}
+}
+
+func TestSectionRegistry(t *testing.T) {
+	registry := DefaultSections.Clone()
+	assert.Equal(t, DefaultSections.Names(), registry.Names())
+
+	extra := &builtinSection{name: "extra", endpoints: []string{"/extra/status"}}
+	require.NoError(t, registry.Register(extra))
+	require.Error(t, registry.Register(extra), "duplicate section")
+
+	_, ok := DefaultSections.Get("extra")
+	assert.False(t, ok, "clone must not modify the original registry")
+
+	sections, err := registry.Resolve([]string{SectionSystem, "extra"})
+	require.NoError(t, err)
+	assert.Equal(t, []Section{mustGetSection(t, registry, SectionSystem), extra}, sections)
+
+	_, err = registry.Resolve([]string{"colect"})
+	require.ErrorContains(t, err, `unknown section "colect"`)
+
+	descs := make(chan *prometheus.Desc, 1000)
+	registry.Describe(descs)
+	close(descs)
+	assert.Len(t, descs, len(NewMetrics()))
+}
+
+func TestParseConfig_UnknownSection(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+devices:
+  - host: "192.168.1.1"
+    collect: [ "system", "modems" ]
+`), 0o600))
+
+	_, err := ParseConfig(file)
+	require.ErrorContains(t, err, `device "192.168.1.1": unknown section "modems"`)
+}
+
+func TestParseConfig_TopProcesses(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+devices:
+  - host: "192.168.1.1"
+    collect: [ "cpu" ]
+  - host: "192.168.1.2"
+    collect: [ "cpu" ]
+    top_processes: 0
+`), 0o600))
+
+	config, err := ParseConfig(file)
+	require.NoError(t, err)
+	require.NotNil(t, config.Devices[0].TopProcesses)
+	assert.Equal(t, 5, *config.Devices[0].TopProcesses)
+	require.NotNil(t, config.Devices[1].TopProcesses)
+	assert.Equal(t, 0, *config.Devices[1].TopProcesses, "0 disables per-process series")
+
+	require.NoError(t, os.WriteFile(file, []byte(`
+devices:
+  - host: "192.168.1.1"
+    collect: [ "cpu" ]
+    top_processes: -1
+`), 0o600))
+
+	_, err = ParseConfig(file)
+	require.ErrorContains(t, err, `device "192.168.1.1": top_processes must not be negative`)
+}
+
+func mustGetSection(t *testing.T, registry *SectionRegistry, name string) Section {
+	t.Helper()
+	section, ok := registry.Get(name)
+	require.True(t, ok)
+	return section
+}
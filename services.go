@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+)
+
+// Service describes where the state of a service can be found in its status endpoint.
+// Adding a new service only requires adding a new entry to the services registry.
+type Service struct {
+	Name       string // service label value
+	Endpoint   string // status endpoint of the service
+	Instance   string // field with instance name, for services with multiple instances (optional)
+	Enabled    string // field with the enabled state
+	Running    string // field with the running state
+	LastUpdate string // field with unix time of the last update (optional)
+}
+
+// services is the registry of services reported by the services section.
+var services = []Service{
+	{Name: "ddns", Endpoint: "/ddns/status", Instance: "name", Enabled: "enabled", Running: "status", LastUpdate: "last_update"},
+	{Name: "snmp", Endpoint: "/snmp/status", Enabled: "enabled", Running: "running"},
+	{Name: "mqtt_broker", Endpoint: "/mqtt/broker/status", Enabled: "enabled", Running: "running"},
+	{Name: "modbus", Endpoint: "/modbus/tcp_server/status", Enabled: "enabled", Running: "running"},
+	{Name: "samba", Endpoint: "/samba/status", Enabled: "enabled", Running: "running"},
+}
+
+// ServiceStatusResponse is a generic response of service status endpoints.
+// Data is either a single object or a list of service instances.
+type ServiceStatusResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data"`
+}
+
+// Instances returns all instances of the service in the response.
+func (r *ServiceStatusResponse) Instances() []map[string]interface{} {
+	switch data := r.Data.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{data}
+	case []interface{}:
+		instances := make([]map[string]interface{}, 0, len(data))
+		for _, item := range data {
+			if instance, ok := item.(map[string]interface{}); ok {
+				instances = append(instances, instance)
+			}
+		}
+		return instances
+	default:
+		return nil
+	}
+}
+
+// serviceStateTrue translates various representations of an active state used by the Web API.
+func serviceStateTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "1", "true", "on", "up", "ok", "running", "active", "enabled", "connected", "success", "updated", "up to date":
+			return true
+		}
+	}
+
+	return false
+}
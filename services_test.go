@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceStatusResponse_Instances(t *testing.T) {
+	var single ServiceStatusResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"success":true,"data":{"enabled":true}}`), &single))
+	assert.Len(t, single.Instances(), 1)
+
+	var multiple ServiceStatusResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"success":true,"data":[{"name":"a"},{"name":"b"},"invalid"]}`), &multiple))
+	assert.Len(t, multiple.Instances(), 2)
+
+	var empty ServiceStatusResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"success":false}`), &empty))
+	assert.Empty(t, empty.Instances())
+}
+
+func TestServiceStateTrue(t *testing.T) {
+	assert.True(t, serviceStateTrue(true))
+	assert.True(t, serviceStateTrue(float64(1)))
+	assert.True(t, serviceStateTrue("1"))
+	assert.True(t, serviceStateTrue("Running"))
+	assert.True(t, serviceStateTrue("Up to date"))
+
+	assert.False(t, serviceStateTrue(false))
+	assert.False(t, serviceStateTrue(float64(0)))
+	assert.False(t, serviceStateTrue("0"))
+	assert.False(t, serviceStateTrue("Update failed"))
+	assert.False(t, serviceStateTrue(nil))
+}
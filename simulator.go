@@ -0,0 +1,228 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	simulatorOptions SimulatorOptions
+	simulatorListen  string // Simulator listen address
+	simulatorTlsCert string // Simulator TLS certificate file
+	simulatorTlsKey  string // Simulator TLS key file
+)
+
+var simulateCmd = cobra.Command{
+	Use:     "simulate",
+	Example: "./teltonika-exporter simulate --fixtures tests --listen :8080 --animate",
+	Short:   "Serve a simulated Teltonika Web API",
+	Long: `Serves a fake Teltonika Web API replaying responses from a fixture directory, as written by the record
+subcommand. Point a device with schema "http" at the listen address to develop dashboards and alerts without a router.
+
+With --animate signal values drift, traffic counters grow and clients join and leave between requests.
+--failure-rate and --latency inject failed requests and slow responses.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := os.Stat(simulatorOptions.Dir); err != nil {
+			return fmt.Errorf("invalid fixture directory: %w", err)
+		}
+
+		srv := &http.Server{
+			Addr:              simulatorListen,
+			Handler:           NewSimulator(simulatorOptions),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		slog.Info("Simulator started", "listen", simulatorListen, "fixtures", simulatorOptions.Dir)
+
+		var err error
+		if simulatorTlsCert != "" {
+			err = srv.ListenAndServeTLS(simulatorTlsCert, simulatorTlsKey)
+		} else {
+			err = srv.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("simulator failed: %w", err)
+		}
+
+		return nil
+	},
+}
+
+// SimulatorOptions configure the simulated Teltonika Web API.
+type SimulatorOptions struct {
+	Dir         string        // fixture directory
+	Animate     bool          // drift signal values, grow counters and let clients come and go
+	FailureRate float64       // share of requests answered with 500
+	Latency     time.Duration // max random delay added to each response
+}
+
+// Simulator is a http.Handler serving the Teltonika Web API from recorded fixtures.
+type Simulator struct {
+	options SimulatorOptions
+
+	mtx      sync.Mutex
+	requests map[string]int     // count of requests per endpoint, drives the animation
+	drift    map[string]float64 // accumulated drift of the signal fields by endpoint and key
+	random   *rand.Rand
+}
+
+func NewSimulator(options SimulatorOptions) *Simulator {
+	return &Simulator{
+		options:  options,
+		requests: make(map[string]int),
+		drift:    make(map[string]float64),
+		random:   rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), //nolint:gosec
+	}
+}
+
+// simulatedMaxDrift limits how far the signal fields drift from the fixture values.
+const simulatedMaxDrift = 10
+
+// simulatedSignalKeys are fields which drift when animated.
+var simulatedSignalKeys = []string{"rssi", "rsrp", "rsrq", "sinr", "signal", "noise", "quality", "latency"}
+
+// simulatedCounterKeys are fields which grow when animated.
+var simulatedCounterKeys = []string{
+	"rx_bytes", "tx_bytes", "rxbytes", "txbytes", "rx_packets", "tx_packets",
+	"bytes_in", "bytes_out", "bytes_received", "bytes_sent", "input_octets", "output_octets",
+	"uptime_seconds", "session_time",
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := strings.CutPrefix(r.URL.Path, "/api")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mtx.Lock()
+	s.requests[endpoint]++
+	tick := s.requests[endpoint]
+	var delay time.Duration
+	if s.options.Latency > 0 {
+		delay = time.Duration(s.random.Int64N(int64(s.options.Latency)))
+	}
+	failed := s.options.FailureRate > 0 && s.random.Float64() < s.options.FailureRate
+	s.mtx.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if endpoint == "/login" {
+		if r.Method != http.MethodPost {
+			http.Error(w, `{"success":false}`, http.StatusMethodNotAllowed)
+			return
+		}
+	} else if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		http.Error(w, `{"success":false}`, http.StatusUnauthorized)
+		return
+	}
+
+	if failed {
+		http.Error(w, `{"success":false}`, http.StatusInternalServerError)
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(s.options.Dir, FixtureFile(endpoint)))
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, `{"success":false}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to read fixture", "endpoint", endpoint, "error", err)
+		http.Error(w, `{"success":false}`, http.StatusInternalServerError)
+		return
+	}
+
+	if s.options.Animate {
+		content = s.animate(endpoint, content, tick)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(content)
+}
+
+// animate changes the fixture as if tick requests were made to the endpoint. Signal fields take
+// a random walk, one step per response, and clients leave and come back.
+func (s *Simulator) animate(endpoint string, content []byte, tick int) []byte {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// every signal field of the response drifts once
+	drifted := make(map[string]bool)
+	scalar := func(key string, value gjson.Result) string {
+		if value.Type != gjson.Number {
+			return value.Raw
+		}
+
+		switch {
+		case slices.Contains(simulatedSignalKeys, key):
+			id := endpoint + "/" + key
+			if !drifted[id] {
+				drifted[id] = true
+				drift := s.drift[id] + s.random.Float64()*2 - 1
+				s.drift[id] = min(max(drift, -simulatedMaxDrift), simulatedMaxDrift)
+			}
+			return formatNumber(value, value.Float()+s.drift[id])
+		case slices.Contains(simulatedCounterKeys, key):
+			step := max(1, value.Float()/1000)
+			return formatNumber(value, value.Float()+float64(tick)*step)
+		default:
+			return value.Raw
+		}
+	}
+
+	// clients are identified by a MAC address: hotspot sessions and DHCP leases are array items with
+	// the address, wireless clients are also keys of the assoclist. A client missing from a response
+	// is missing from all of its lists.
+	present := make(map[string]bool)
+	keep := func(key string, value gjson.Result) bool {
+		mac := key
+		if key == "" {
+			mac = cmp.Or(value.Get("mac").String(), value.Get("macaddr").String())
+		}
+		if !macAddress.MatchString(mac) {
+			return true
+		}
+
+		mac = strings.ToUpper(mac)
+		if _, ok := present[mac]; !ok {
+			present[mac] = s.random.Float64() < 0.8
+		}
+		return present[mac]
+	}
+
+	return rewriteJSON(content, scalar, keep)
+}
+
+// formatNumber formats the value like the original JSON number, integers stay integers.
+func formatNumber(original gjson.Result, value float64) string {
+	if !strings.ContainsAny(original.Raw, ".eE") {
+		return strconv.FormatInt(int64(value), 10)
+	}
+
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func simulatorRequest(t *testing.T, server *httptest.Server, method, endpoint string, authorized bool) (int, string) {
+	t.Helper()
+
+	request, err := http.NewRequestWithContext(t.Context(), method, server.URL+"/api"+endpoint, nil)
+	require.NoError(t, err)
+	if authorized {
+		request.Header.Set("Authorization", "Bearer secret_token")
+	}
+
+	response, err := server.Client().Do(request)
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response.StatusCode, string(body)
+}
+
+func TestSimulator(t *testing.T) {
+	server := httptest.NewServer(NewSimulator(SimulatorOptions{Dir: "tests"}))
+	defer server.Close()
+
+	status, body := simulatorRequest(t, server, http.MethodPost, "/login", false)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "secret_token", gjson.Get(body, "data.token").String())
+
+	status, _ = simulatorRequest(t, server, http.MethodGet, "/modems/status", false)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, body = simulatorRequest(t, server, http.MethodGet, "/modems/status", true)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, gjson.Get(body, "success").Bool())
+
+	status, _ = simulatorRequest(t, server, http.MethodGet, "/samba/status", true)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestSimulator_Failures(t *testing.T) {
+	server := httptest.NewServer(NewSimulator(SimulatorOptions{Dir: "tests", FailureRate: 1}))
+	defer server.Close()
+
+	status, _ := simulatorRequest(t, server, http.MethodGet, "/modems/status", true)
+	assert.Equal(t, http.StatusInternalServerError, status)
+}
+
+func TestSimulator_Animate(t *testing.T) {
+	s := NewSimulator(SimulatorOptions{Dir: "tests", Animate: true})
+	s.random = rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	// counts of clients and the signal seen in every response
+	wireless := make(map[int64]bool)
+	leases := make(map[int64]bool)
+	var rsrp []float64
+	var rxBytes []int64
+	for range 50 {
+		_, body := simulatorRequest(t, server, http.MethodGet, "/wireless/interfaces/status", true)
+		assoclist := gjson.Get(body, "data.0.assoclist").Map()
+		wireless[int64(len(assoclist))] = true
+		for _, client := range gjson.Get(body, "data.0.clients.#.macaddr").Array() {
+			assert.Contains(t, assoclist, client.String(), "a client leaves all lists")
+		}
+
+		_, body = simulatorRequest(t, server, http.MethodGet, "/dhcp/leases/ipv4/status", true)
+		leases[gjson.Get(body, "data.#").Int()] = true
+
+		_, body = simulatorRequest(t, server, http.MethodGet, "/modems/status", true)
+		rsrp = append(rsrp, gjson.Get(body, "data.0.rsrp").Float())
+		assert.False(t, strings.Contains(gjson.Get(body, "data.0.rsrp").Raw, "."), "integers stay integers")
+		assert.Equal(t, "Connected", gjson.Get(body, "data.0.state").String())
+
+		_, body = simulatorRequest(t, server, http.MethodGet, "/hotspot/sessions/status", true)
+		if session := gjson.Get(body, `data.#(mac=="aa:bb:cc:dd:00:33")`); session.Exists() {
+			rxBytes = append(rxBytes, session.Get("input_octets").Int())
+		}
+	}
+
+	assert.Greater(t, len(wireless), 1, "wireless clients should come and go")
+	assert.Greater(t, len(leases), 1, "DHCP clients should come and go")
+
+	// the signal takes a random walk away from the fixture value, within the limit
+	var drifted bool
+	for _, value := range rsrp {
+		assert.InDelta(t, -83, value, simulatedMaxDrift+1)
+		drifted = drifted || math.Abs(value+83) > 2
+	}
+	assert.True(t, drifted, "the drift should accumulate")
+
+	require.Greater(t, len(rxBytes), 1)
+	assert.Positive(t, rxBytes[len(rxBytes)-1]-rxBytes[0], "counters should grow")
+}
+
+func TestSimulator_Latency(t *testing.T) {
+	const latency = 200 * time.Millisecond
+	s := NewSimulator(SimulatorOptions{Dir: "tests", Latency: latency})
+	s.random = rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+
+	// the first random number of the same seed is the delay of the first request
+	expected := time.Duration(rand.New(rand.NewPCG(1, 2)).Int64N(int64(latency))) //nolint:gosec
+	require.Positive(t, expected)
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	start := time.Now()
+	status, _ := simulatorRequest(t, server, http.MethodGet, "/modems/status", true)
+	assert.Equal(t, http.StatusOK, status)
+	assert.GreaterOrEqual(t, time.Since(start), expected, "the response should be delayed")
+}
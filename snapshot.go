@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DeviceSnapshot is the result of the last collection of a device, as served by the status API. Scrapes
+// and pushes collect independently, the snapshot is stored by whichever collection ran last.
+type DeviceSnapshot struct {
+	Name           string            `json:"name"`
+	Host           string            `json:"host"`
+	LoggedIn       bool              `json:"logged_in"`
+	LastCollection *time.Time        `json:"last_collection,omitempty"`
+	LastSuccess    *time.Time        `json:"last_success,omitempty"`
+	LastError      string            `json:"last_error,omitempty"`
+	LastErrorTime  *time.Time        `json:"last_error_time,omitempty"`
+	Info           *DeviceInfo       `json:"info,omitempty"`
+	Sections       []SectionSnapshot `json:"sections"`
+}
+
+// DeviceInfo is the static device information, see teltonika_device_info.
+type DeviceInfo struct {
+	Model       string `json:"model"`
+	ProductCode string `json:"product_code"`
+	Serial      string `json:"serial"`
+	Firmware    string `json:"firmware"`
+	Hostname    string `json:"hostname"`
+	Batch       string `json:"batch"`
+}
+
+// SectionSnapshot holds the metrics a section produced during the last collection.
+type SectionSnapshot struct {
+	Name    string           `json:"name"`
+	Error   string           `json:"error,omitempty"`
+	Metrics []MetricSnapshot `json:"metrics"`
+}
+
+// MetricSnapshot is a single collected sample. Histograms have their sample count and sum as value.
+type MetricSnapshot struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"` // without the device label
+	Value  float64           `json:"value"`
+	Count  uint64            `json:"count,omitempty"`
+}
+
+// collectSection collects the section, forwarding its metrics to ch unless it is nil,
+// and returns snapshots of the collected metrics.
+func (d *Device) collectSection(section Section, ch chan<- prometheus.Metric) []MetricSnapshot {
+	names := d.sectionMetricNames(section)
+	sectionCh := make(chan prometheus.Metric)
+	done := make(chan []MetricSnapshot)
+	go func() {
+		metrics := []MetricSnapshot{}
+		for metric := range sectionCh {
+			if ch != nil {
+				ch <- metric
+			}
+
+			if snapshot, ok := snapshotMetric(names[metric.Desc()], metric); ok {
+				metrics = append(metrics, snapshot)
+			}
+		}
+		done <- metrics
+	}()
+
+	section.Collect(d, sectionCh)
+	close(sectionCh)
+
+	return <-done
+}
+
+// Snapshot returns the result of the last collection of the device.
+func (d *Device) Snapshot() DeviceSnapshot {
+	d.snapshotMtx.RLock()
+	defer d.snapshotMtx.RUnlock()
+
+	snapshot := d.snapshot
+	snapshot.Name = d.name
+	snapshot.Host = d.host
+	if snapshot.Sections == nil {
+		snapshot.Sections = []SectionSnapshot{}
+	}
+
+	return snapshot
+}
+
+// storeSnapshot replaces the snapshot of the device, keeping the time of the last success and error.
+func (d *Device) storeSnapshot(snapshot DeviceSnapshot, collected time.Time, err string) {
+	d.snapshotMtx.Lock()
+	defer d.snapshotMtx.Unlock()
+
+	snapshot.LastCollection = &collected
+	snapshot.LastSuccess = d.snapshot.LastSuccess
+	snapshot.LastError = d.snapshot.LastError
+	snapshot.LastErrorTime = d.snapshot.LastErrorTime
+
+	if err == "" {
+		snapshot.LastSuccess = &collected
+	} else {
+		snapshot.LastError = err
+		snapshot.LastErrorTime = &collected
+	}
+
+	d.snapshot = snapshot
+}
+
+// deviceInfo returns the cached static device information, nil when it is not known.
+func (d *Device) deviceInfo() *DeviceInfo {
+	if d.info == nil {
+		return nil
+	}
+
+	return &DeviceInfo{
+		Model:       d.info.Data.Static.Model,
+		ProductCode: d.info.Data.Mnfinfo.Name,
+		Serial:      d.info.Data.Mnfinfo.Serial,
+		Firmware:    d.info.Data.Static.FwVersion,
+		Hostname:    d.info.Data.Static.Hostname,
+		Batch:       d.info.Data.Mnfinfo.Batch,
+	}
+}
+
+// sectionMetricNames returns the names of the section metrics by their descriptor, the
+// descriptor does not expose the name.
+func (d *Device) sectionMetricNames(section Section) map[*prometheus.Desc]string {
+	names := make(map[*prometheus.Desc]string)
+	switch section := section.(type) {
+	case *builtinSection:
+		for _, name := range section.metrics {
+			names[builtinMetrics[name]] = name
+			if desc, ok := d.metrics[name]; ok {
+				names[desc] = name
+			}
+		}
+	case *CustomCollector:
+		for _, metric := range section.metrics {
+			names[metric.desc] = metric.name
+		}
+	}
+
+	return names
+}
+
+func snapshotMetric(name string, metric prometheus.Metric) (MetricSnapshot, bool) {
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		slog.Error("failed to read collected metric", "error", err)
+		return MetricSnapshot{}, false
+	}
+
+	snapshot := MetricSnapshot{Name: name}
+	for _, label := range m.GetLabel() {
+		if label.GetName() == "device" {
+			continue
+		}
+
+		if snapshot.Labels == nil {
+			snapshot.Labels = make(map[string]string)
+		}
+		snapshot.Labels[label.GetName()] = label.GetValue()
+	}
+
+	switch {
+	case m.Gauge != nil:
+		snapshot.Value = m.GetGauge().GetValue()
+	case m.Counter != nil:
+		snapshot.Value = m.GetCounter().GetValue()
+	case m.Histogram != nil:
+		snapshot.Value = m.GetHistogram().GetSampleSum()
+		snapshot.Count = m.GetHistogram().GetSampleCount()
+	default:
+		snapshot.Value = m.GetUntyped().GetValue()
+	}
+
+	// JSON has no representation of these
+	if math.IsNaN(snapshot.Value) || math.IsInf(snapshot.Value, 0) {
+		return MetricSnapshot{}, false
+	}
+
+	return snapshot, true
+}
+
+// NewStatusApi returns a handler of the JSON status API serving the device snapshots.
+func NewStatusApi(collector *Collector) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/devices", func(w http.ResponseWriter, r *http.Request) {
+		snapshots := make([]DeviceSnapshot, len(collector.devices))
+		for i, device := range collector.devices {
+			snapshots[i] = device.Snapshot()
+		}
+
+		writeJson(w, http.StatusOK, snapshots)
+	})
+
+	mux.HandleFunc("GET /api/v1/devices/{name}", func(w http.ResponseWriter, r *http.Request) {
+		for _, device := range collector.devices {
+			if device.name == r.PathValue("name") {
+				writeJson(w, http.StatusOK, device.Snapshot())
+				return
+			}
+		}
+
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "device not found"})
+	})
+
+	return mux
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("failed to write JSON response", "error", err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotTestSections(t *testing.T) []Section {
+	t.Helper()
+	return mustResolveSections(t, DefaultSections, SectionSystem, SectionModem, SectionServices, SectionIo)
+}
+
+func TestDevice_Snapshot(t *testing.T) {
+	api := simulatorTransport(t, "tests", testsOptionalEndpoints...)
+	failing := false
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if failing && strings.HasSuffix(req.URL.Path, "/io/status") {
+			return &http.Response{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway", Body: http.NoBody, Header: http.Header{}}, nil
+		}
+		return api.RoundTrip(req)
+	}), snapshotTestSections(t)...)
+
+	snapshot := d.Snapshot()
+	assert.Nil(t, snapshot.LastCollection, "nothing collected yet")
+	assert.Empty(t, snapshot.Sections)
+
+	count := testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect))
+
+	snapshot = d.Snapshot()
+	assert.True(t, snapshot.LoggedIn)
+	require.NotNil(t, snapshot.LastSuccess)
+	assert.Equal(t, mockNow(), *snapshot.LastSuccess)
+	assert.Empty(t, snapshot.LastError)
+	require.NotNil(t, snapshot.Info)
+	assert.Equal(t, "RUTX_R_00.07.13.1", snapshot.Info.Firmware)
+	assert.Equal(t, "1111111111", snapshot.Info.Serial)
+
+	// the snapshot holds the same samples as the scrape
+	total := 0
+	for _, section := range snapshot.Sections {
+		total += len(section.Metrics)
+		for _, metric := range section.Metrics {
+			assert.Contains(t, builtinMetrics, metric.Name)
+		}
+	}
+	assert.Equal(t, count, total)
+
+	require.Len(t, snapshot.Sections, 4)
+	assert.Equal(t, SectionModem, snapshot.Sections[1].Name)
+	assert.Contains(t, snapshot.Sections[1].Metrics, MetricSnapshot{Name: "teltonika_mobile_rsrp", Labels: map[string]string{"sim": "2-1"}, Value: -83})
+	assert.Empty(t, snapshot.Sections[2].Error, "missing optional services are not an error")
+
+	failing = true
+	testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect))
+
+	snapshot = d.Snapshot()
+	assert.Equal(t, "/api/io/status: 502 Bad Gateway", snapshot.Sections[3].Error)
+	assert.Equal(t, "io: /api/io/status: 502 Bad Gateway", snapshot.LastError)
+	require.NotNil(t, snapshot.LastSuccess, "the last success is kept")
+}
+
+func TestDevice_SnapshotInvalidResponse(t *testing.T) {
+	api := fixtureTransport(t)
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/io/status") {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"success":false}`)), Header: http.Header{}}, nil
+		}
+		return api.RoundTrip(req)
+	}), snapshotTestSections(t)...)
+
+	testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect))
+
+	snapshot := d.Snapshot()
+	assert.Equal(t, "/api/io/status: request was not successful", snapshot.Sections[3].Error)
+	assert.Equal(t, "io: /api/io/status: request was not successful", snapshot.LastError)
+	assert.Nil(t, snapshot.LastSuccess)
+}
+
+func TestDevice_SnapshotUnsupportedSection(t *testing.T) {
+	api := simulatorTransport(t, "tests", testsOptionalEndpoints...)
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/io/status") {
+			return &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: http.NoBody, Header: http.Header{}}, nil
+		}
+		return api.RoundTrip(req)
+	}), snapshotTestSections(t)...)
+
+	testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect))
+
+	snapshot := d.Snapshot()
+	assert.Empty(t, snapshot.Sections[3].Error)
+	assert.Empty(t, snapshot.LastError)
+	require.NotNil(t, snapshot.LastSuccess, "a section missing on the device is not a failure")
+}
+
+func TestDevice_SnapshotLoginFailure(t *testing.T) {
+	d := testDevice(t, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized", Body: http.NoBody, Header: http.Header{}}, nil
+	}), snapshotTestSections(t)...)
+
+	testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect))
+
+	snapshot := d.Snapshot()
+	assert.False(t, snapshot.LoggedIn)
+	assert.Nil(t, snapshot.LastSuccess)
+	assert.Equal(t, "authentication failed: 401 Unauthorized", snapshot.LastError)
+}
+
+func TestStatusApi(t *testing.T) {
+	d := testDevice(t, fixtureTransport(t), snapshotTestSections(t)...)
+	testutil.CollectAndCount(prometheus.CollectorFunc(d.Collect))
+
+	server := httptest.NewServer(NewStatusApi(&Collector{devices: []*Device{d}}))
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + "/api/v1/devices")
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+
+	var snapshots []DeviceSnapshot
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&snapshots))
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, "RUT007", snapshots[0].Name)
+	assert.Equal(t, "RUTX50", snapshots[0].Info.Model)
+
+	response, err = server.Client().Get(server.URL + "/api/v1/devices/RUT007")
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	var snapshot DeviceSnapshot
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&snapshot))
+	assert.Len(t, snapshot.Sections, 4)
+
+	response, err = server.Client().Get(server.URL + "/api/v1/devices/unknown")
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
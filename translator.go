@@ -5,6 +5,7 @@ import "strings"
 type Translator struct {
 	mac   map[string]string
 	radio map[string]string
+	vpn   map[string]string
 }
 
 func (t *Translator) TranslateMac(mac string) string {
@@ -26,3 +27,13 @@ func (t *Translator) TranslateRadio(radio string) string {
 
 	return radio
 }
+
+func (t *Translator) TranslateVpn(name string) string {
+	for key, value := range t.vpn {
+		if strings.EqualFold(name, key) {
+			return value
+		}
+	}
+
+	return name
+}
@@ -33,3 +33,16 @@ func TestTranslator_TranslateRadio(t *testing.T) {
 	assert.Equal(t, "wifi_5", trans.TranslateRadio("radio1"))
 	assert.Equal(t, "unknown_radio", trans.TranslateRadio("unknown_radio"))
 }
+
+func TestTranslator_TranslateVpn(t *testing.T) {
+	trans := Translator{
+		vpn: map[string]string{
+			"wg0":        "hq",
+			"Branch-Srv": "branch",
+		},
+	}
+
+	assert.Equal(t, "hq", trans.TranslateVpn("wg0"))
+	assert.Equal(t, "branch", trans.TranslateVpn("branch-srv")) // case-insensitive
+	assert.Equal(t, "unknown_tunnel", trans.TranslateVpn("unknown_tunnel"))
+}
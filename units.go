@@ -0,0 +1,11 @@
+package main
+
+// Sizes in bytes of the units used by the Web API. The router reports memory sizes in binary
+// units, although the web UI labels them as MB.
+const (
+	UnitKiB = 1 << 10
+	UnitMiB = 1 << 20
+)
+
+// systemMemoryUnit is the unit of all memory and flash sizes in /system/device/usage/status.
+const systemMemoryUnit = UnitMiB
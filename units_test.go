@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDevice_CollectUnits checks the conversion of the fixture values to bytes: memory and flash are
+// reported in MiB, process memory in KiB and the mobile data usage in bytes.
+func TestDevice_CollectUnits(t *testing.T) {
+	d := testDevice(t, fixtureTransport(t))
+	d.token = "secret_token"
+	d.topProcesses = 4
+
+	tests := []struct {
+		name     string
+		collect  func(ch chan<- prometheus.Metric)
+		expected string
+	}{
+		{
+			name:    "memory",
+			collect: d.collectSystemDeviceUsageStatus,
+			expected: `
+# HELP teltonika_flash_total Total amount of flash memory in bytes
+# TYPE teltonika_flash_total gauge
+teltonika_flash_total{device="RUT007"} 8.994684928e+07
+# HELP teltonika_ram_shared Amount of shared system memory in bytes
+# TYPE teltonika_ram_shared gauge
+teltonika_ram_shared{device="RUT007"} 524288
+# HELP teltonika_ram_total Total amount of system memory in bytes
+# TYPE teltonika_ram_total gauge
+teltonika_ram_total{device="RUT007"} 2.5279070208e+08
+`,
+		},
+		{
+			name:    "processes",
+			collect: d.collectProcessesStatus,
+			expected: `
+# HELP teltonika_process_memory_bytes Memory used by the processes with the name in bytes
+# TYPE teltonika_process_memory_bytes gauge
+teltonika_process_memory_bytes{device="RUT007",name="dnsmasq"} 2.097152e+06
+teltonika_process_memory_bytes{device="RUT007",name="gsmd"} 1.4680064e+07
+teltonika_process_memory_bytes{device="RUT007",name="procd"} 1.691648e+06
+teltonika_process_memory_bytes{device="RUT007",name="uhttpd"} 5.24288e+06
+`,
+		},
+		{
+			name:    "mobile usage",
+			collect: d.collectModemStatus,
+			expected: `
+# HELP teltonika_mobile_data_received Received data in bytes
+# TYPE teltonika_mobile_data_received gauge
+teltonika_mobile_data_received{device="RUT007",sim="2-1"} 4.5844315341e+10
+# HELP teltonika_mobile_data_sent Sent data in bytes
+# TYPE teltonika_mobile_data_sent gauge
+teltonika_mobile_data_sent{device="RUT007",sim="2-1"} 1.658175509e+09
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, line := range strings.Split(tt.expected, "\n") {
+				if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+					names = append(names, strings.Fields(name)[0])
+				}
+			}
+
+			err := testutil.CollectAndCompare(prometheus.CollectorFunc(tt.collect), strings.NewReader(tt.expected), names...)
+			require.NoError(t, err)
+		})
+	}
+}